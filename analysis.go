@@ -0,0 +1,260 @@
+package escposimg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// BlackCoverage returns the fraction (0.0-1.0) of pixels in a dithered image that print black.
+// Pixels with a gray level below 128 are counted as black, matching the ESC/POS packing.
+func BlackCoverage(img image.Image) float64 {
+	bounds := img.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 0
+	}
+
+	black := 0
+	if gray, ok := img.(*image.Gray); ok {
+		// Fast path: read the pixel buffer directly
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := gray.Pix[gray.PixOffset(bounds.Min.X, y):gray.PixOffset(bounds.Max.X, y)]
+			for _, v := range row {
+				if v < 128 {
+					black++
+				}
+			}
+		}
+	} else {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 128 {
+					black++
+				}
+			}
+		}
+	}
+
+	return float64(black) / float64(total)
+}
+
+// ditherMetricsBlockSize is the size of the pixel blocks averaged when comparing a
+// dithered image to its source, approximating how the eye blends printed dots
+const ditherMetricsBlockSize = 4
+
+// DitherMetrics describes how well a dithered image reproduces its source
+type DitherMetrics struct {
+	// Fraction of pixels printed black (see BlackCoverage)
+	Coverage float64
+
+	// Absolute difference between the coverage and the average darkness of the
+	// source (0.0-1.0), lower means the overall tone is better preserved
+	ToneError float64
+
+	// Mean absolute difference of the local darkness in small blocks (0.0-1.0),
+	// lower means shading and detail are better preserved
+	DetailError float64
+
+	// Overall score (0.0-1.0) combining tone and detail error, higher is better
+	Score float64
+}
+
+// MeasureDithering compares a dithered image with the grayscale source it was
+// produced from. Both images must have the same size.
+func MeasureDithering(source, dithered image.Image) (DitherMetrics, error) {
+	sb, db := source.Bounds(), dithered.Bounds()
+	if sb.Dx() != db.Dx() || sb.Dy() != db.Dy() {
+		return DitherMetrics{}, fmt.Errorf("image sizes differ: %dx%d and %dx%d", sb.Dx(), sb.Dy(), db.Dx(), db.Dy())
+	}
+	if sb.Empty() {
+		return DitherMetrics{}, fmt.Errorf("cannot measure an empty image")
+	}
+
+	gray := convertToGrayscale(source)
+	metrics := DitherMetrics{Coverage: BlackCoverage(dithered)}
+
+	var darkness, detailError float64
+	blocks := 0
+	for by := 0; by < sb.Dy(); by += ditherMetricsBlockSize {
+		for bx := 0; bx < sb.Dx(); bx += ditherMetricsBlockSize {
+			var sourceDark, printedDark float64
+			n := 0
+			for y := by; y < min(by+ditherMetricsBlockSize, sb.Dy()); y++ {
+				for x := bx; x < min(bx+ditherMetricsBlockSize, sb.Dx()); x++ {
+					sourceDark += 1 - float64(gray[y][x])/255
+					if color.GrayModel.Convert(dithered.At(db.Min.X+x, db.Min.Y+y)).(color.Gray).Y < 128 {
+						printedDark++
+					}
+					n++
+				}
+			}
+			darkness += sourceDark
+			detailError += math.Abs(sourceDark-printedDark) / float64(n)
+			blocks++
+		}
+	}
+
+	metrics.ToneError = math.Abs(metrics.Coverage - darkness/float64(sb.Dx()*sb.Dy()))
+	metrics.DetailError = detailError / float64(blocks)
+	metrics.Score = 1 - (metrics.ToneError+metrics.DetailError)/2
+	return metrics, nil
+}
+
+// RecommendDithering dithers an image with every algorithm and returns the one
+// with the highest DitherMetrics.Score, along with the metrics of all algorithms.
+// The image is scaled as configured; config.DitheringAlgo is ignored.
+func RecommendDithering(imagePath string, config *Config) (DitheringType, map[DitheringType]DitherMetrics, error) {
+	img, err := LoadImage(imagePath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to load image: %w", err)
+	}
+	source, _, err := layoutImage(img, config, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	results := make(map[DitheringType]DitherMetrics)
+	best, bestScore := DitheringFloydSteinberg, math.Inf(-1)
+	for _, algo := range DitheringTypes() {
+		algoConfig := *config
+		algoConfig.DitheringAlgo = algo
+
+		dithered, err := ApplyDitheringConfig(source, &algoConfig)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to apply %s dithering: %w", algo, err)
+		}
+		metrics, err := MeasureDithering(source, dithered)
+		if err != nil {
+			return 0, nil, err
+		}
+		results[algo] = metrics
+		logger().Debug("Measured dithering", "algorithm", algo.String(), "score", metrics.Score,
+			"tone_error", metrics.ToneError, "detail_error", metrics.DetailError)
+
+		if metrics.Score > bestScore {
+			best, bestScore = algo, metrics.Score
+		}
+	}
+
+	return best, results, nil
+}
+
+// DitherFingerprint dithers an image with the given algorithm and the dithering
+// settings of cfg (threshold, gamma, etc.; nil uses the defaults) and returns a
+// hex-encoded SHA-256 hash of the result and its size. Storing the fingerprint in
+// tests detects when a library upgrade changes the dithering output.
+//
+// The image is not scaled. An empty string is returned if dithering fails.
+func DitherFingerprint(img image.Image, algo DitheringType, cfg *Config) string {
+	algoConfig := Config{}
+	if cfg != nil {
+		algoConfig = *cfg
+	}
+	algoConfig.DitheringAlgo = algo
+
+	dithered, err := ApplyDitheringConfig(img, &algoConfig)
+	if err != nil {
+		logger().Warn("Failed to dither image for fingerprint", "algorithm", algo.String(), "error", err)
+		return ""
+	}
+	packed, err := convertToRasterFormat(dithered)
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.New()
+	bounds := dithered.Bounds()
+	fmt.Fprintf(hash, "%dx%d\n", bounds.Dx(), bounds.Dy())
+	hash.Write(packed)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Limits used by CheckLegibility
+const (
+	legibilityMaxDownscale = 4.0 // Downscale factor above which fine detail blurs
+	legibilityMaxUpscale   = 2.0 // Upscale factor above which the print looks soft
+	legibilityMinHeight    = 16  // Printed height in dots below which text is unreadable
+	legibilityMinContrast  = 64  // Spread of the gray levels below which tones look flat
+)
+
+// CheckLegibility returns human-readable advisories about conditions that are likely
+// to make an image print illegibly with the given configuration, such as a large
+// downscale factor blurring small text or a source resolution too low for the
+// printer. An empty result means no problems were found. The image is not modified.
+func CheckLegibility(img image.Image, config *Config) []string {
+	bounds := img.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	if width == 0 || height == 0 {
+		return []string{"image is empty"}
+	}
+	if config.Rotation == 90 || config.Rotation == 270 {
+		width, height = height, width
+	}
+	if config.RotateDegrees != 0 {
+		sin, cos := math.Sincos(config.RotateDegrees * math.Pi / 180)
+		width, height = width*math.Abs(cos)+height*math.Abs(sin), width*math.Abs(sin)+height*math.Abs(cos)
+	}
+
+	// Mirror the scaling of the processing pipeline
+	targetWidth := float64(config.CalculatePixelWidth() - config.PaddingPx.Left - config.PaddingPx.Right)
+	scale := targetWidth / width
+	if config.NoScale {
+		scale = min(scale, 1)
+	}
+
+	var advisories []string
+	if scale > 0 && 1/scale > legibilityMaxDownscale {
+		advisories = append(advisories, fmt.Sprintf(
+			"downscale factor %.1fx exceeds %.0fx and may blur fine detail such as small text", 1/scale, legibilityMaxDownscale))
+	}
+	if scale > legibilityMaxUpscale {
+		advisories = append(advisories, fmt.Sprintf(
+			"source resolution is low for the target size: %.0f px are enlarged %.1fx to %.0f dots at %d DPI",
+			width, scale, targetWidth, config.HorizontalDPI()))
+	}
+	if printed := height * scale * config.VerticalScale(); printed > 0 && printed < legibilityMinHeight {
+		advisories = append(advisories, fmt.Sprintf(
+			"image prints only %.0f dots (%.1f mm) high, too small for legible text",
+			printed, printed*25.4/float64(config.VerticalDPI())))
+	}
+	if low, high := grayRange(img); high-low < legibilityMinContrast {
+		advisories = append(advisories, fmt.Sprintf(
+			"low contrast (gray levels %d-%d) may print as a flat pattern; consider increasing the contrast", low, high))
+	}
+	return advisories
+}
+
+// grayRange returns the 5th and 95th percentile of the gray levels of an image,
+// sampling at most about 256x256 pixels
+func grayRange(img image.Image) (low, high int) {
+	bounds := img.Bounds()
+	stepX := max(bounds.Dx()/256, 1)
+	stepY := max(bounds.Dy()/256, 1)
+
+	var histogram [256]int
+	total := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			histogram[color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y]++
+			total++
+		}
+	}
+
+	count := 0
+	low, high = -1, 255
+	for level, n := range histogram {
+		count += n
+		if low < 0 && count > total*5/100 {
+			low = level
+		}
+		if count >= total*95/100 {
+			high = level
+			break
+		}
+	}
+	return max(low, 0), high
+}
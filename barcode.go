@@ -0,0 +1,255 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BarcodeType is the GS k barcode system (the printer's m value)
+type BarcodeType int
+
+// Barcode systems of the length-prefixed GS k form (function B)
+const (
+	BarcodeUPCA    BarcodeType = 65
+	BarcodeUPCE    BarcodeType = 66
+	BarcodeEAN13   BarcodeType = 67
+	BarcodeEAN8    BarcodeType = 68
+	BarcodeCODE39  BarcodeType = 69
+	BarcodeITF     BarcodeType = 70
+	BarcodeCODABAR BarcodeType = 71
+	BarcodeCODE93  BarcodeType = 72
+	BarcodeCODE128 BarcodeType = 73
+)
+
+// HRIPosition is where the human readable text of a barcode is printed
+type HRIPosition int
+
+const (
+	// HRIBelow prints the text below the barcode (GS H 2)
+	HRIBelow HRIPosition = iota
+	// HRIAbove prints the text above the barcode (GS H 1)
+	HRIAbove
+	// HRIBoth prints the text above and below the barcode (GS H 3)
+	HRIBoth
+	// HRINone prints no text (GS H 0)
+	HRINone
+)
+
+// gsH returns the n parameter of GS H for the position
+func (h HRIPosition) gsH() byte {
+	switch h {
+	case HRIAbove:
+		return 1
+	case HRIBoth:
+		return 3
+	case HRINone:
+		return 0
+	default:
+		return 2
+	}
+}
+
+// GenerateBarcode generates the commands printing a barcode: the bar height
+// (GS h, from config.BarcodeHeightDots when set), the position of the human
+// readable text (GS H, config.BarcodeHRI) and the barcode itself (GS k), aligned
+// as config.Alignment. CODE128 data without a code set prefix is sent as code
+// set B; UPC, EAN and CODE39 data is validated.
+//
+// The commands contain no initialization, feed or cut, so they can be written to
+// an output before or after the commands of an image printed with CutPaper unset.
+func GenerateBarcode(data string, barcodeType BarcodeType, config *Config) ([]byte, error) {
+	if config.Dialect == DialectStar {
+		return nil, fmt.Errorf("barcodes are not supported for Star printers")
+	}
+
+	var buf bytes.Buffer
+	if err := writeBarcodeConfig(&buf, barcodeType, data, config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBarcodeConfig writes a barcode with the height, text position and
+// alignment of the configuration
+func writeBarcodeConfig(buf *bytes.Buffer, barcodeType BarcodeType, data string, config *Config) error {
+	if config.BarcodeHeightDots < 0 || config.BarcodeHeightDots > 255 {
+		return fmt.Errorf("invalid barcode height %d dots (expected 1-255)", config.BarcodeHeightDots)
+	}
+	if barcodeType == BarcodeCODE128 && data != "" && !strings.HasPrefix(data, "{") {
+		// CODE128 data starts with the code set; B covers printable ASCII
+		data = "{B" + data
+	}
+
+	writeJustification(buf, config)
+	if config.BarcodeHeightDots > 0 {
+		// GS h n: barcode height in dots
+		buf.Write([]byte{GS, 'h', byte(config.BarcodeHeightDots)})
+	}
+	if err := writeBarcode(buf, int(barcodeType), data, config.BarcodeHRI); err != nil {
+		return err
+	}
+	resetJustification(buf, config)
+	return nil
+}
+
+// writeBarcode writes a GS k barcode command using the length-prefixed form
+// (function B), where barcodeType is the printer's m value from 65 (UPC-A) to
+// 73 (CODE128), preceded by GS H selecting the position of the human readable text
+func writeBarcode(buf *bytes.Buffer, barcodeType int, data string, hri HRIPosition) error {
+	if barcodeType < 65 || barcodeType > 73 {
+		return fmt.Errorf("unsupported barcode type %d (expected 65-73)", barcodeType)
+	}
+	if len(data) == 0 || len(data) > 255 {
+		return fmt.Errorf("barcode data must be 1-255 bytes, got %d", len(data))
+	}
+	if err := validateBarcodeData(BarcodeType(barcodeType), data); err != nil {
+		return err
+	}
+
+	// GS H n: position of the human readable characters
+	buf.Write([]byte{GS, 'H', hri.gsH()})
+
+	// GS k m n d1...dn
+	buf.Write([]byte{GS, 'k', byte(barcodeType), byte(len(data))})
+	buf.WriteString(data)
+
+	logger().Debug("Added barcode", "type", barcodeType, "data", data)
+	return nil
+}
+
+// validateBarcodeData checks the data of barcode systems with a fixed format;
+// the data of the other systems is left to the printer
+func validateBarcodeData(barcodeType BarcodeType, data string) error {
+	var lengths []int
+	switch barcodeType {
+	case BarcodeUPCA:
+		lengths = []int{11, 12}
+	case BarcodeUPCE:
+		lengths = []int{6, 7, 8, 11, 12}
+	case BarcodeEAN13:
+		lengths = []int{12, 13}
+	case BarcodeEAN8:
+		lengths = []int{7, 8}
+	case BarcodeCODE39:
+		for _, c := range data {
+			if !strings.ContainsRune("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./", c) {
+				return fmt.Errorf("invalid CODE39 character %q in barcode data %q", c, data)
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	for _, c := range data {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("barcode data %q must contain digits only", data)
+		}
+	}
+	if !slices.Contains(lengths, len(data)) {
+		return fmt.Errorf("barcode data %q has %d digits, expected one of %v", data, len(data), lengths)
+	}
+	// EAN data with the check digit must carry the correct one
+	if (barcodeType == BarcodeEAN13 && len(data) == 13) || (barcodeType == BarcodeEAN8 && len(data) == 8) {
+		if check := eanCheckDigit(data[:len(data)-1]); data[len(data)-1] != check {
+			return fmt.Errorf("invalid check digit in barcode data %q (expected %c)", data, check)
+		}
+	}
+	return nil
+}
+
+// eanCheckDigit returns the check digit of EAN digits without it, weighting the
+// digits 3 and 1 alternately from the right
+func eanCheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// writeImageBarcode writes the barcode configured with Config.BarcodeData, if any
+func writeImageBarcode(buf *bytes.Buffer, config *Config) error {
+	if config.BarcodeData == "" {
+		return nil
+	}
+	barcodeType := config.BarcodeType
+	if barcodeType == 0 {
+		barcodeType = BarcodeCODE128
+	}
+	if err := writeBarcodeConfig(buf, barcodeType, config.BarcodeData, config); err != nil {
+		return fmt.Errorf("failed to write barcode: %w", err)
+	}
+	return nil
+}
+
+// withBarcodeMeta returns config with BarcodeData taken from the image metadata
+// key BarcodeMetaKey, or config itself if the image does not carry the key
+func withBarcodeMeta(imageData []byte, config *Config) *Config {
+	if config.BarcodeMetaKey == "" {
+		return config
+	}
+	value, ok := pngText(imageData, config.BarcodeMetaKey)
+	if !ok || value == "" {
+		logger().Debug("Image carries no barcode metadata", "key", config.BarcodeMetaKey)
+		return config
+	}
+	logger().Debug("Barcode read from image metadata", "key", config.BarcodeMetaKey, "data", value)
+	withBarcode := *config
+	withBarcode.BarcodeData = value
+	return &withBarcode
+}
+
+// QR code error correction levels of GenerateQRCode, recovering about 7%, 15%,
+// 25% and 30% of the code words
+const (
+	QRErrorCorrectionL byte = 48
+	QRErrorCorrectionM byte = 49
+	QRErrorCorrectionQ byte = 50
+	QRErrorCorrectionH byte = 51
+)
+
+// maxQRCodeData is the number of bytes a model 2 QR code holds at most
+const maxQRCodeData = 7089
+
+// GenerateQRCode generates the GS ( k commands printing data as a model 2 QR code:
+// model selection, module size, error correction level, storing the data and
+// printing it. size is the width of a module in dots (1-16) and errorCorrection
+// one of the QRErrorCorrection levels.
+//
+// Like GenerateBarcode the commands contain no initialization, feed or cut, so
+// they can be written to an output after the commands of an image.
+func GenerateQRCode(data string, size byte, errorCorrection byte) ([]byte, error) {
+	if size < 1 || size > 16 {
+		return nil, fmt.Errorf("invalid QR code module size %d (expected 1-16)", size)
+	}
+	if errorCorrection < QRErrorCorrectionL || errorCorrection > QRErrorCorrectionH {
+		return nil, fmt.Errorf("invalid QR code error correction level %d (expected 48-51)", errorCorrection)
+	}
+	if len(data) == 0 || len(data) > maxQRCodeData {
+		return nil, fmt.Errorf("QR code data must be 1-%d bytes, got %d", maxQRCodeData, len(data))
+	}
+
+	var buf bytes.Buffer
+
+	// GS ( k pL pH cn fn ...: cn=49 selects QR codes
+	buf.Write([]byte{GS, '(', 'k', 4, 0, 49, 65, 50, 0})           // fn 65: model 2
+	buf.Write([]byte{GS, '(', 'k', 3, 0, 49, 67, size})            // fn 67: module size
+	buf.Write([]byte{GS, '(', 'k', 3, 0, 49, 69, errorCorrection}) // fn 69: error correction level
+
+	// fn 80: store the data; pL pH count the cn, fn and m bytes as well
+	storeLen := len(data) + 3
+	buf.Write([]byte{GS, '(', 'k', byte(storeLen), byte(storeLen >> 8), 49, 80, 48})
+	buf.WriteString(data)
+
+	buf.Write([]byte{GS, '(', 'k', 3, 0, 49, 81, 48}) // fn 81: print the stored data
+
+	logger().Debug("Generated QR code", "data_size", len(data), "module_size", size, "error_correction", errorCorrection)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package escposimg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateBarcode(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		barcodeType BarcodeType
+		config      func(*Config)
+		want        []byte
+	}{
+		{"ean13", "4006381333931", BarcodeEAN13, func(c *Config) {},
+			append([]byte{GS, 'H', 2, GS, 'k', 67, 13}, "4006381333931"...)},
+		{"ean13 without check digit", "400638133393", BarcodeEAN13, func(c *Config) {},
+			append([]byte{GS, 'H', 2, GS, 'k', 67, 12}, "400638133393"...)},
+		{"height and hri above", "12345670", BarcodeEAN8, func(c *Config) { c.BarcodeHeightDots = 100; c.BarcodeHRI = HRIAbove },
+			append([]byte{GS, 'h', 100, GS, 'H', 1, GS, 'k', 68, 8}, "12345670"...)},
+		{"code128 hri both", "AB-1", BarcodeCODE128, func(c *Config) { c.BarcodeHRI = HRIBoth },
+			append([]byte{GS, 'H', 3, GS, 'k', 73, 6}, "{BAB-1"...)},
+		{"code128 code set", "{C1234", BarcodeCODE128, func(c *Config) { c.BarcodeHRI = HRINone },
+			append([]byte{GS, 'H', 0, GS, 'k', 73, 6}, "{C1234"...)},
+		{"centered", "CODE 39", BarcodeCODE39, func(c *Config) { c.Alignment = AlignCenter },
+			append(append([]byte{ESC, 'a', 1, GS, 'H', 2, GS, 'k', 69, 7}, "CODE 39"...), ESC, 'a', 0)},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+		data, err := GenerateBarcode(tt.data, tt.barcodeType, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateBarcode() error = %v", tt.name, err)
+		}
+		if !bytes.Equal(data, tt.want) {
+			t.Errorf("%s: GenerateBarcode() = % X, want % X", tt.name, data, tt.want)
+		}
+	}
+}
+
+func TestGenerateBarcodeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		barcodeType BarcodeType
+		config      func(*Config)
+	}{
+		{"ean13 check digit", "4006381333932", BarcodeEAN13, func(c *Config) {}},
+		{"ean13 length", "40063813339", BarcodeEAN13, func(c *Config) {}},
+		{"ean13 letters", "40063813339A", BarcodeEAN13, func(c *Config) {}},
+		{"ean8 check digit", "12345678", BarcodeEAN8, func(c *Config) {}},
+		{"code39 lowercase", "abc", BarcodeCODE39, func(c *Config) {}},
+		{"empty", "", BarcodeCODE128, func(c *Config) {}},
+		{"unknown type", "123", BarcodeType(80), func(c *Config) {}},
+		{"height", "123", BarcodeCODE128, func(c *Config) { c.BarcodeHeightDots = 256 }},
+		{"star", "123", BarcodeCODE128, func(c *Config) { c.Dialect = DialectStar }},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+		if data, err := GenerateBarcode(tt.data, tt.barcodeType, config); err == nil {
+			t.Errorf("%s: GenerateBarcode() = % X, want an error", tt.name, data)
+		}
+	}
+}
+
+func TestGenerateQRCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		size   byte
+		ec     byte
+		pL, pH byte
+	}{
+		{"short", "https://example.com", 6, QRErrorCorrectionM, 22, 0},
+		{"252 bytes", string(bytes.Repeat([]byte{'a'}, 252)), 1, QRErrorCorrectionL, 255, 0},
+		{"253 bytes", string(bytes.Repeat([]byte{'a'}, 253)), 16, QRErrorCorrectionH, 0, 1},
+		{"maximum", string(bytes.Repeat([]byte{'1'}, maxQRCodeData)), 3, QRErrorCorrectionQ, 0xB4, 0x1B},
+	}
+	for _, tt := range tests {
+		data, err := GenerateQRCode(tt.data, tt.size, tt.ec)
+		if err != nil {
+			t.Fatalf("%s: GenerateQRCode() error = %v", tt.name, err)
+		}
+		var want []byte
+		want = append(want, GS, '(', 'k', 4, 0, 49, 65, 50, 0)
+		want = append(want, GS, '(', 'k', 3, 0, 49, 67, tt.size)
+		want = append(want, GS, '(', 'k', 3, 0, 49, 69, tt.ec)
+		want = append(want, GS, '(', 'k', tt.pL, tt.pH, 49, 80, 48)
+		want = append(want, tt.data...)
+		want = append(want, GS, '(', 'k', 3, 0, 49, 81, 48)
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: GenerateQRCode() differs from the expected commands", tt.name)
+		}
+		if got := int(tt.pL) + int(tt.pH)<<8; got != len(tt.data)+3 {
+			t.Errorf("%s: store length %d, want %d", tt.name, got, len(tt.data)+3)
+		}
+
+		// The stream parses into the five GS ( k functions
+		if names := commandNames(t, data); len(names) != 5 {
+			t.Errorf("%s: parsed %d commands, want 5", tt.name, len(names))
+		}
+	}
+}
+
+func TestGenerateQRCodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		size byte
+		ec   byte
+	}{
+		{"empty", "", 3, QRErrorCorrectionM},
+		{"too long", string(bytes.Repeat([]byte{'1'}, maxQRCodeData+1)), 3, QRErrorCorrectionM},
+		{"size zero", "x", 0, QRErrorCorrectionM},
+		{"size 17", "x", 17, QRErrorCorrectionM},
+		{"error correction", "x", 3, 52},
+	}
+	for _, tt := range tests {
+		if _, err := GenerateQRCode(tt.data, tt.size, tt.ec); err == nil {
+			t.Errorf("%s: GenerateQRCode() returned no error", tt.name)
+		}
+	}
+}
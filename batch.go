@@ -0,0 +1,55 @@
+package escposimg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OutputFactory creates the output method for an input image
+type OutputFactory func(inputName string) (OutputMethod, error)
+
+// NamingFileOutputFactory returns an OutputFactory that writes each input to a file
+// in dir named after the input without its extension plus suffix, e.g. "photo.jpg"
+// becomes "dir/photo.escpos" with suffix ".escpos" (the default when suffix is empty).
+// The directory is created if it does not exist.
+func NamingFileOutputFactory(dir, suffix string) func(inputName string) (OutputMethod, error) {
+	if suffix == "" {
+		suffix = ".escpos"
+	}
+	return func(inputName string) (OutputMethod, error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+		base := filepath.Base(inputName)
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+		output, err := NewFileOutput(filepath.Join(dir, base+suffix))
+		if err != nil {
+			return nil, err
+		}
+		return output, nil
+	}
+}
+
+// ProcessBatch processes several images, sending each to the output created for it
+// by the factory. All images are processed even if some fail; the returned error
+// joins the errors of all failed images.
+func ProcessBatch(imagePaths []string, config *Config, factory OutputFactory) error {
+	var errs []error
+	for _, imagePath := range imagePaths {
+		output, err := factory(imagePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to create output: %w", imagePath, err))
+			continue
+		}
+		if err := ProcessImage(imagePath, config, output); err != nil {
+			output.Close()
+			errs = append(errs, fmt.Errorf("%s: %w", imagePath, err))
+		}
+	}
+
+	logger().Info("Batch processing completed", "images", len(imagePaths), "failed", len(errs))
+	return errors.Join(errs...)
+}
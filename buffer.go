@@ -0,0 +1,72 @@
+package escposimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+)
+
+// Limits of the downloaded bit image (GS * x y), whose size is given in blocks of 8x8 dots
+const (
+	maxDownloadedImageWidth  = 255  // x
+	maxDownloadedImageHeight = 48   // y
+	maxDownloadedImageBlocks = 1536 // x * y
+)
+
+// PrintBufferedImage generates the commands to print the image kept in the
+// printer's memory by the last job generated with Config.PreserveBuffer (GS / m),
+// followed by the usual paper feed and an optional cut (see Config.CutPaper).
+//
+// No image data is sent, so the image can be reprinted with a handful of bytes.
+// The job must not start with ESC @, which would clear the image.
+func PrintBufferedImage(config *Config) []byte {
+	var buf bytes.Buffer
+
+	// GS / m: print the downloaded bit image at the configured scale and alignment
+	writeJustification(&buf, config)
+	buf.Write([]byte{GS, '/', byte(config.RasterScale)})
+	resetJustification(&buf, config)
+
+	writeFinalFeed(&buf, 3, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Buffered image print command generated", "total_bytes", buf.Len())
+	return buf.Bytes()
+}
+
+// fitsDownloadedImage reports whether an image of the given size fits into the
+// printer's downloaded bit image memory
+func fitsDownloadedImage(width, height int) bool {
+	x, y := (width+7)/8, (height+7)/8
+	return x <= maxDownloadedImageWidth && y <= maxDownloadedImageHeight && x*y <= maxDownloadedImageBlocks
+}
+
+// writeDownloadedImage defines a monochrome image as downloaded bit image (GS *)
+// and prints it with GS / m. The image must fit (see fitsDownloadedImage).
+func writeDownloadedImage(buf *bytes.Buffer, img image.Image, scale RasterScale) {
+	bounds := img.Bounds()
+	x, y := (bounds.Dx()+7)/8, (bounds.Dy()+7)/8
+
+	// GS * x y d1...d(x*y*8): the data runs column by column, each column as y
+	// bytes from top to bottom with the most significant bit as the top dot
+	buf.Write([]byte{GS, '*', byte(x), byte(y)})
+	for col := 0; col < x*8; col++ {
+		for block := 0; block < y; block++ {
+			var b byte
+			for bit := 0; bit < 8; bit++ {
+				px, py := bounds.Min.X+col, bounds.Min.Y+block*8+bit
+				if px < bounds.Max.X && py < bounds.Max.Y && color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y < 128 {
+					b |= 0x80 >> bit
+				}
+			}
+			buf.WriteByte(b)
+		}
+	}
+
+	// GS / m: print the downloaded bit image
+	buf.Write([]byte{GS, '/', byte(scale)})
+
+	logger().Debug("Wrote downloaded bit image", "width_blocks", x, "height_blocks", y)
+}
@@ -0,0 +1,45 @@
+package escposimg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPreserveBufferAlignment(t *testing.T) {
+	img := solidImage(16, 16, 0)
+	tests := []struct {
+		name  string
+		align Alignment
+		n     byte
+	}{
+		{"center", AlignCenter, 1},
+		{"right", AlignRight, 2},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PreserveBuffer = true
+		config.Alignment = tt.align
+
+		var buf bytes.Buffer
+		if err := writeRasterImage(&buf, img, config); err != nil {
+			t.Fatalf("%s: writeRasterImage() error = %v", tt.name, err)
+		}
+		data := buf.Bytes()
+		justify := bytes.Index(data, []byte{ESC, 'a', tt.n})
+		printAt := bytes.Index(data, []byte{GS, '/', 0})
+		reset := bytes.LastIndex(data, []byte{ESC, 'a', 0})
+		if justify < 0 || printAt < 0 || justify > printAt || reset < printAt {
+			t.Errorf("%s: ESC a %d at %d, GS / at %d, ESC a 0 at %d, want GS / between them",
+				tt.name, tt.n, justify, printAt, reset)
+		}
+		if bytes.Contains(data, []byte{GS, 'v', '0'}) {
+			t.Errorf("%s: preserved image also printed with GS v 0", tt.name)
+		}
+
+		reprint := PrintBufferedImage(config)
+		want := []byte{ESC, 'a', tt.n, GS, '/', 0, ESC, 'a', 0}
+		if !bytes.HasPrefix(reprint, want) {
+			t.Errorf("%s: PrintBufferedImage() = % X, want prefix % X", tt.name, reprint, want)
+		}
+	}
+}
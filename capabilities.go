@@ -0,0 +1,107 @@
+package escposimg
+
+import "slices"
+
+// Feature identifies a configuration option whose effect depends on the print mode
+type Feature int
+
+const (
+	// FeatureRasterScale is printer-side enlargement (Config.RasterScale)
+	FeatureRasterScale Feature = iota
+	// FeatureSmoothing is printer-side smoothing (Config.Smoothing)
+	FeatureSmoothing
+	// FeatureChecksum is a checksum after the image data (Config.AppendChecksum)
+	FeatureChecksum
+	// FeatureTiling is splitting the image into several receipts (Config.TileHeightPx)
+	FeatureTiling
+	// FeaturePreserveBuffer is keeping the image in the printer's memory (Config.PreserveBuffer)
+	FeaturePreserveBuffer
+)
+
+// Features returns all known features
+func Features() []Feature {
+	return []Feature{FeatureRasterScale, FeatureSmoothing, FeatureChecksum, FeatureTiling, FeaturePreserveBuffer}
+}
+
+// String returns the string representation of the feature
+func (f Feature) String() string {
+	switch f {
+	case FeatureRasterScale:
+		return "raster-scale"
+	case FeatureSmoothing:
+		return "smoothing"
+	case FeatureChecksum:
+		return "checksum"
+	case FeatureTiling:
+		return "tiling"
+	case FeaturePreserveBuffer:
+		return "preserve-buffer"
+	default:
+		return "unknown"
+	}
+}
+
+// Capabilities lists the features supported by a print mode
+type Capabilities struct {
+	Mode     PrintMode
+	Features []Feature
+}
+
+// Supports reports whether the capabilities include the feature
+func (c Capabilities) Supports(feature Feature) bool {
+	return slices.Contains(c.Features, feature)
+}
+
+// ModeCapabilities returns the features supported by a print mode.
+//
+// Two-color printing (Config.RedMaskPath) is not listed as it always uses the
+// graphics commands, independent of the print mode.
+func ModeCapabilities(mode PrintMode) Capabilities {
+	switch mode {
+	case PrintModeRaster:
+		return Capabilities{Mode: mode, Features: []Feature{FeatureRasterScale, FeatureSmoothing, FeatureChecksum, FeatureTiling, FeaturePreserveBuffer}}
+	case PrintModeBitImage:
+		return Capabilities{Mode: mode, Features: []Feature{FeatureSmoothing, FeatureChecksum, FeatureTiling}}
+	default:
+		return Capabilities{Mode: mode}
+	}
+}
+
+// Supports reports whether the print mode supports the feature
+func (p PrintMode) Supports(feature Feature) bool {
+	return ModeCapabilities(p).Supports(feature)
+}
+
+// usedFeatures returns the mode-dependent features enabled in the configuration
+func (c *Config) usedFeatures() []Feature {
+	var used []Feature
+	if c.RasterScale != RasterScaleNormal {
+		used = append(used, FeatureRasterScale)
+	}
+	if c.Smoothing {
+		used = append(used, FeatureSmoothing)
+	}
+	if c.AppendChecksum {
+		used = append(used, FeatureChecksum)
+	}
+	if c.TileHeightPx > 0 {
+		used = append(used, FeatureTiling)
+	}
+	if c.PreserveBuffer {
+		used = append(used, FeaturePreserveBuffer)
+	}
+	return used
+}
+
+// checkFeatures warns about enabled options that the print mode ignores
+func (c *Config) checkFeatures() error {
+	for _, feature := range c.usedFeatures() {
+		if !c.PrintMode.Supports(feature) {
+			if err := c.warn("Option is not supported in the selected print mode and will be ignored",
+				"feature", feature.String(), "print_mode", c.PrintMode.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
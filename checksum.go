@@ -0,0 +1,65 @@
+package escposimg
+
+import "bytes"
+
+// ChecksumType selects the integrity marker appended after image data
+type ChecksumType int
+
+const (
+	// ChecksumXOR appends a single byte: the XOR of all image data bytes
+	ChecksumXOR ChecksumType = iota
+
+	// ChecksumCRC16 appends two bytes (big-endian): CRC-16/CCITT-FALSE
+	// (polynomial 0x1021, initial value 0xFFFF) over the image data
+	ChecksumCRC16
+)
+
+// String returns the string representation of the checksum type
+func (c ChecksumType) String() string {
+	switch c {
+	case ChecksumXOR:
+		return "xor"
+	case ChecksumCRC16:
+		return "crc16"
+	default:
+		return "unknown"
+	}
+}
+
+// Checksum computes the integrity marker for image data
+func Checksum(data []byte, checksumType ChecksumType) []byte {
+	switch checksumType {
+	case ChecksumCRC16:
+		crc := crc16CCITT(data)
+		return []byte{byte(crc >> 8), byte(crc)}
+	default:
+		var sum byte
+		for _, b := range data {
+			sum ^= b
+		}
+		return []byte{sum}
+	}
+}
+
+// writeChecksum appends the checksum of the image data to the command buffer
+func writeChecksum(buf *bytes.Buffer, data []byte, checksumType ChecksumType) {
+	checksum := Checksum(data, checksumType)
+	buf.Write(checksum)
+	logger().Debug("Appended image checksum", "type", checksumType.String(), "checksum", checksum)
+}
+
+// crc16CCITT computes CRC-16/CCITT-FALSE
+func crc16CCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
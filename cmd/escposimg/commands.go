@@ -0,0 +1,365 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/72nd/escposimg"
+)
+
+// runProcess implements the "process" subcommand
+func runProcess(args []string) error {
+	fs := flag.NewFlagSet("process", flag.ExitOnError)
+	imagePath := fs.String("image", "", "Path to the image file (required)")
+	configFlags := addConfigFlags(fs)
+	outputFlags := addOutputFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	version := fs.Bool("version", false, "Show version information")
+	checkLegibility := fs.Bool("check-legibility", false, "Warn about conditions that may make the image print illegibly")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s process [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Process an image and send the ESC/POS commands to an output.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s process -image photo.jpg\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s process -image photo.jpg -output network -network-addr 192.168.1.100:9100\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s process -image photo.jpg -dithering threshold -debug-output\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s process -image photo.jpg -print-mode bit-image\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s process -image photo.jpg -print-mode raster -dithering atkinson\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	// Show version and exit
+	if *version {
+		fmt.Printf("escposimg version %s\n", escposimg.Version())
+		return nil
+	}
+
+	setupLogging(*verbose)
+
+	// Validate required arguments
+	if *imagePath == "" {
+		fmt.Fprintf(os.Stderr, "Error: -image is required\n\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config, err := configFlags.config()
+	if err != nil {
+		return err
+	}
+
+	if *checkLegibility {
+		img, err := escposimg.LoadImage(*imagePath)
+		if err != nil {
+			return err
+		}
+		for _, advisory := range escposimg.CheckLegibility(img, config) {
+			slog.Warn("Legibility: " + advisory)
+		}
+	}
+
+	output, err := outputFlags.create()
+	if err != nil {
+		return err
+	}
+
+	// Process the image
+	if err := escposimg.ProcessImage(*imagePath, config, output); err != nil {
+		return fmt.Errorf("failed to process image: %w", err)
+	}
+
+	slog.Info("Image processed successfully")
+	return nil
+}
+
+// runTestPattern implements the "test-pattern" subcommand
+func runTestPattern(args []string) error {
+	fs := flag.NewFlagSet("test-pattern", flag.ExitOnError)
+	paperWidth := fs.Int("paper-width", 80, "Paper width in millimeters")
+	dpi := fs.Int("dpi", 203, "Printer DPI")
+	height := fs.Int("height", 200, "Height of the pattern in dots")
+	outputFlags := addOutputFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Parse(args)
+
+	setupLogging(*verbose)
+
+	config := &escposimg.Config{PaperWidthMM: *paperWidth, DPI: *dpi}
+	data := escposimg.GenerateTestPattern(config.CalculatePixelWidth(), *height)
+
+	output, err := outputFlags.create()
+	if err != nil {
+		return err
+	}
+	if err := output.Write(data); err != nil {
+		output.Close()
+		return fmt.Errorf("failed to write test pattern: %w", err)
+	}
+	return output.Close()
+}
+
+// runGrid implements the "grid" subcommand
+func runGrid(args []string) error {
+	fs := flag.NewFlagSet("grid", flag.ExitOnError)
+	paperWidth := fs.Int("paper-width", 80, "Paper width in millimeters")
+	dpi := fs.Int("dpi", 203, "Printer DPI")
+	square := fs.Int("square", 10, "Size of the grid squares in dots")
+	cutPaper := fs.Bool("cut", false, "Send paper cut command after printing")
+	outputFlags := addOutputFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Parse(args)
+
+	setupLogging(*verbose)
+
+	config := &escposimg.Config{PaperWidthMM: *paperWidth, DPI: *dpi, CutPaper: *cutPaper}
+	data := escposimg.GenerateGrid(config, *square)
+
+	output, err := outputFlags.create()
+	if err != nil {
+		return err
+	}
+	if err := output.Write(data); err != nil {
+		output.Close()
+		return fmt.Errorf("failed to write grid: %w", err)
+	}
+	return output.Close()
+}
+
+// runGradient implements the "gradient" subcommand
+func runGradient(args []string) error {
+	fs := flag.NewFlagSet("gradient", flag.ExitOnError)
+	configFlags := addConfigFlags(fs)
+	outputFlags := addOutputFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Parse(args)
+
+	setupLogging(*verbose)
+
+	config, err := configFlags.config()
+	if err != nil {
+		return err
+	}
+	data := escposimg.GenerateGradient(config)
+
+	output, err := outputFlags.create()
+	if err != nil {
+		return err
+	}
+	if err := output.Write(data); err != nil {
+		output.Close()
+		return fmt.Errorf("failed to write gradient: %w", err)
+	}
+	return output.Close()
+}
+
+// runCompare implements the "compare" subcommand
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	imagePath := fs.String("image", "", "Path to the image file (required)")
+	algorithms := fs.String("algorithms", "", "Comma-separated dithering algorithms to compare (default: all)")
+	zipPath := fs.String("zip", "", "Write the results of all algorithms as PNGs into this zip file instead of printing")
+	configFlags := addConfigFlags(fs)
+	outputFlags := addOutputFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Parse(args)
+
+	setupLogging(*verbose)
+
+	if *imagePath == "" {
+		fmt.Fprintf(os.Stderr, "Error: -image is required\n\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *zipPath != "" {
+		config, err := configFlags.config()
+		if err != nil {
+			return err
+		}
+		file, err := os.Create(*zipPath)
+		if err != nil {
+			return fmt.Errorf("failed to create zip file: %w", err)
+		}
+		if err := escposimg.CompareDitheringZip(*imagePath, config, file); err != nil {
+			file.Close()
+			return err
+		}
+		slog.Info("Comparison saved", "path", *zipPath)
+		return file.Close()
+	}
+
+	var algos []escposimg.DitheringType
+	if *algorithms != "" {
+		for _, name := range strings.Split(*algorithms, ",") {
+			algo, err := parseDitheringAlgo(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			algos = append(algos, algo)
+		}
+	}
+
+	config, err := configFlags.config()
+	if err != nil {
+		return err
+	}
+	data, err := escposimg.GenerateAlgorithmComparison(*imagePath, algos, config)
+	if err != nil {
+		return err
+	}
+
+	output, err := outputFlags.create()
+	if err != nil {
+		return err
+	}
+	if err := output.Write(data); err != nil {
+		output.Close()
+		return fmt.Errorf("failed to write comparison: %w", err)
+	}
+	return output.Close()
+}
+
+// runInspect implements the "inspect" subcommand
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s inspect <file.escpos>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Dump the commands contained in an ESC/POS file.\n")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	commands, err := escposimg.ParseESCPOS(data)
+	for _, cmd := range commands {
+		fmt.Println(cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse ESC/POS data: %w", err)
+	}
+	return nil
+}
+
+// runDiff implements the "diff" subcommand
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff <a.escpos> <b.escpos>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show the command-level differences between two ESC/POS files.\n")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	a, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	b, err := os.ReadFile(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	diffs, err := escposimg.DiffESCPOS(a, b)
+	if err != nil {
+		return err
+	}
+	for _, diff := range diffs {
+		fmt.Println(diff)
+	}
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runPreview implements the "preview" subcommand
+func runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	imagePath := fs.String("image", "", "Path to the image file (required)")
+	outPath := fs.String("out", "preview.png", "Path of the PNG file to write")
+	previewASCII := fs.Bool("preview-ascii", false, "Print the dithered image as ASCII art instead of saving a PNG")
+	asciiWidth := fs.Int("ascii-width", 80, "Maximum width of the ASCII art in characters")
+	receipt := fs.Bool("receipt", false, "Render the full receipt including text, feeds and cut")
+	configFlags := addConfigFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Parse(args)
+
+	setupLogging(*verbose)
+
+	if *imagePath == "" {
+		fmt.Fprintf(os.Stderr, "Error: -image is required\n\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config, err := configFlags.config()
+	if err != nil {
+		return err
+	}
+
+	var img image.Image
+	if *receipt {
+		img, err = escposimg.ReceiptPreview(*imagePath, config)
+	} else {
+		img, err = escposimg.PrepareImage(*imagePath, config)
+	}
+	if err != nil {
+		return err
+	}
+	if *previewASCII {
+		fmt.Print(escposimg.ASCIIPreview(img, *asciiWidth))
+		return nil
+	}
+	if err := escposimg.SaveDebugImage(img, *outPath); err != nil {
+		return err
+	}
+
+	slog.Info("Preview saved", "path", *outPath)
+	return nil
+}
+
+// runManifest implements the "manifest" subcommand
+func runManifest(args []string) error {
+	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
+	outputFlags := addOutputFlags(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s manifest [options] <manifest.json>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print a multi-part receipt described by a JSON manifest.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	setupLogging(*verbose)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	output, err := outputFlags.create()
+	if err != nil {
+		return err
+	}
+	return escposimg.ProcessManifest(fs.Arg(0), output)
+}
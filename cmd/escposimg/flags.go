@@ -0,0 +1,377 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/72nd/escposimg"
+)
+
+// configFlags holds the flags that map into an escposimg.Config
+type configFlags struct {
+	paperWidth     *int
+	widthDots      *int
+	pageWidth      *int
+	offsetX        *int
+	align          *string
+	dpi            *int
+	dpiX           *int
+	dpiY           *int
+	rotate         *int
+	rotateDegrees  *float64
+	scaleFilter    *string
+	heightRounding *string
+	noScale        *bool
+	ditheringAlgo  *string
+	accurateGray   *bool
+	gamma          *float64
+	invert         *bool
+	serpentine     *bool
+	diffusionScale *float64
+	bayerSize      *int
+	threshold      *uint
+	printMode      *string
+	bitImageMode   *int
+	dialect        *string
+	preserveBuffer *bool
+	rasterScale    *string
+	smoothing      *bool
+	motionUnitX    *int
+	motionUnitY    *int
+	padTop         *int
+	padRight       *int
+	padBottom      *int
+	padLeft        *int
+	topRule        *bool
+	bottomRule     *bool
+	ruleHeight     *int
+	separator      *string
+	transfer       *bool
+	flipH          *bool
+	flipV          *bool
+	redMask        *string
+	blackDithering *string
+	redDithering   *string
+	debugOutput    *bool
+	debugImagePath *string
+	debugText      *string
+	barcodeData    *string
+	barcodeType    *int
+	barcodeHeight  *int
+	barcodeHRI     *string
+	barcodeMetaKey *string
+	reverseVideo   *bool
+	cutPaper       *bool
+	cutType        *string
+	noCutter       *bool
+	tearLine       *bool
+	skipFinalFeed  *bool
+	feedLines      *int
+	fileComment    *bool
+	maxBytes       *int
+	maxTime        *time.Duration
+	initDelay      *time.Duration
+	tileHeight     *int
+	regMarks       *bool
+	blankLabels    *int
+	labelHeight    *float64
+	strict         *bool
+	quiet          *bool
+}
+
+// addConfigFlags registers the configuration flags on a flag set
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		paperWidth:     fs.Int("paper-width", 80, "Paper width in millimeters"),
+		widthDots:      fs.Int("width-dots", 0, "Printable width in dots, overriding -paper-width and -dpi (0 calculates it)"),
+		pageWidth:      fs.Int("page-width-dots", 0, "Width of the page in dots; a narrower image is centered on it without scaling (0 disables)"),
+		offsetX:        fs.Int("offset-x", 0, "Distance of the image from the left edge of the page in dots (with -page-width-dots, 0 centers)"),
+		align:          fs.String("align", "left", "Position of images narrower than the paper (left, center, right)"),
+		dpi:            fs.Int("dpi", 203, "Printer DPI"),
+		dpiX:           fs.Int("dpi-x", 0, "Horizontal printer DPI for non-square dots (defaults to -dpi)"),
+		dpiY:           fs.Int("dpi-y", 0, "Vertical printer DPI for non-square dots (defaults to -dpi)"),
+		rotate:         fs.Int("rotate", 0, "Rotate the image clockwise by 0, 90, 180 or 270 degrees before scaling"),
+		rotateDegrees:  fs.Float64("rotate-degrees", 0, "Rotate the image clockwise by this angle before scaling (e.g. to deskew scans)"),
+		scaleFilter:    fs.String("scale-filter", "lanczos3", "Scaling filter (lanczos3, bilinear, nearest, area)"),
+		heightRounding: fs.String("height-rounding", "round", "Rounding of the scaled image height (round, floor, ceil)"),
+		noScale:        fs.Bool("no-scale", false, "Print the image at its original size (images wider than the paper are still scaled down)"),
+		ditheringAlgo:  fs.String("dithering", "floyd-steinberg", "Dithering algorithm (floyd-steinberg, atkinson, threshold, bayer, burkes, sierra-lite, jarvis-judice-ninke, shadura, stucki, sierra, halftone, adaptive)"),
+		threshold:      fs.Uint("threshold", 0, "Gray level (1-255) below which pixels print black (0 uses the algorithm default)"),
+		accurateGray:   fs.Bool("accurate-gray", false, "Convert to gray in linear light (BT.709) for more accurate tones"),
+		gamma:          fs.Float64("gamma", 2.2, "Gamma used to linearize colors before grayscale conversion (0 or 1 disables)"),
+		invert:         fs.Bool("invert", false, "Print the negative of the image (e.g. for white-on-black logos)"),
+		serpentine:     fs.Bool("serpentine", false, "Alternate the scan direction of error-diffusion dithering every row"),
+		diffusionScale: fs.Float64("diffusion-scale", 1.0, "Factor applied to the error distributed by error-diffusion dithering (e.g., 0.8 lighter, 1.2 punchier)"),
+		bayerSize:      fs.Int("bayer-size", 4, "Size of the Bayer matrix for -dithering bayer (2, 4 or 8)"),
+		printMode:      fs.String("print-mode", "raster", "ESC/POS print mode (raster, bit-image)"),
+		bitImageMode:   fs.Int("bit-image-mode", 0, "ESC * mode in bit-image print mode (0: 8-dot, 32: 24-dot single density, 33: 24-dot double density)"),
+		dialect:        fs.String("dialect", "epson", "Printer command set (epson, star)"),
+		preserveBuffer: fs.Bool("preserve-buffer", false, "Keep small raster images in the printer's memory (GS * / GS /) for reprinting"),
+		rasterScale:    fs.String("raster-scale", "normal", "Printer-side enlargement in raster mode (normal, double-width, double-height, quadruple)"),
+		smoothing:      fs.Bool("smoothing", false, "Enable printer smoothing mode (GS b, not supported by all printers)"),
+		motionUnitX:    fs.Int("motion-unit-x", 0, "Horizontal motion unit set with GS P as 1/x inch (0-255, 0 keeps the printer default)"),
+		motionUnitY:    fs.Int("motion-unit-y", 0, "Vertical motion unit set with GS P as 1/y inch (0-255, 0 keeps the printer default)"),
+		padTop:         fs.Int("pad-top", 0, "Whitespace above the image in pixels"),
+		padRight:       fs.Int("pad-right", 0, "Whitespace right of the image in pixels"),
+		padBottom:      fs.Int("pad-bottom", 0, "Whitespace below the image in pixels"),
+		padLeft:        fs.Int("pad-left", 0, "Whitespace left of the image in pixels"),
+		topRule:        fs.Bool("top-rule", false, "Print a full-width rule above the image"),
+		bottomRule:     fs.Bool("bottom-rule", false, "Print a full-width rule below the image"),
+		ruleHeight:     fs.Int("rule-height", escposimg.DefaultRuleHeightPx, "Height of the rules in pixels"),
+		separator:      fs.String("separator", "solid", "Pattern of the rules (solid, dots, dashes, wave, double-line)"),
+		transfer:       fs.Bool("transfer", false, "Print the image mirrored for iron-on transfer media"),
+		flipH:          fs.Bool("flip-h", false, "Mirror the image left-to-right"),
+		flipV:          fs.Bool("flip-v", false, "Mirror the image top-to-bottom"),
+		redMask:        fs.String("red-mask", "", "Mask image whose non-white pixels print red on two-color paper"),
+		blackDithering: fs.String("black-dithering", "", "Dithering algorithm for the black plane of two-color output (default: -dithering)"),
+		redDithering:   fs.String("red-dithering", "", "Dithering algorithm for the red mask of two-color output (default: print every non-white mask pixel red)"),
+		debugOutput:    fs.Bool("debug-output", false, "Save dithered image for debugging"),
+		debugImagePath: fs.String("debug-image", "debug_output.png", "Path to save debug image"),
+		debugText:      fs.String("debug-text", "", "Optional debug text to print before image"),
+		barcodeData:    fs.String("barcode-data", "", "Print a barcode with this data below the image"),
+		barcodeType:    fs.Int("barcode-type", 73, "GS k barcode system of -barcode-data, from 65 (UPC-A) to 73 (CODE128)"),
+		barcodeHeight:  fs.Int("barcode-height", 0, "Height of the barcode bars in dots (1-255, 0 keeps the printer default)"),
+		barcodeHRI:     fs.String("barcode-hri", "below", "Position of the barcode text (below, above, both, none)"),
+		barcodeMetaKey: fs.String("barcode-meta-key", "", "Print a barcode with the value of this PNG text metadata key, if the image has it"),
+		reverseVideo:   fs.Bool("reverse-text", false, "Print text white on black (GS B)"),
+		cutPaper:       fs.Bool("cut", false, "Send paper cut command after printing"),
+		cutType:        fs.String("cut-type", "", "Kind of cut after printing (none, partial, full); partial or full cut even without -cut"),
+		noCutter:       fs.Bool("no-cutter", false, "The printer has no cutter: never send cut commands, even with -cut"),
+		tearLine:       fs.Bool("tear-line", false, "Print a dashed tear line instead of cutting (with -no-cutter)"),
+		skipFinalFeed:  fs.Bool("skip-final-feed", false, "Do not feed paper after the image (with -cut unset, output ends with the image data)"),
+		feedLines:      fs.Int("feed-lines", -1, "Line feeds after the image, before the cut (-1 keeps the default of 3, or 2 in bit-image mode)"),
+		fileComment:    fs.Bool("file-comment", false, "Write a .meta file with source, settings and timestamp next to the output file"),
+		maxBytes:       fs.Int("max-bytes", 0, "Reduce the image width until the output fits into this many bytes (0 disables the limit)"),
+		maxTime:        fs.Duration("max-time", 0, "Abort when scaling and dithering take longer than this (e.g. 10s, 0 disables the limit)"),
+		initDelay:      fs.Duration("init-delay", 0, "Pause after the printer reset before sending the job (e.g. 100ms)"),
+		tileHeight:     fs.Int("tile-height", 0, "Split the image into receipts of this height in pixels (0 disables tiling)"),
+		regMarks:       fs.Bool("registration-marks", false, "Add alignment marks above and below each tile"),
+		blankLabels:    fs.Int("blank-labels", 0, "Number of blank labels to feed after the image (requires -label-height)"),
+		labelHeight:    fs.Float64("label-height", 0, "Height of one label in millimeters, for -blank-labels"),
+		strict:         fs.Bool("strict", false, "Treat warnings (e.g. upscaling) as errors"),
+		quiet:          fs.Bool("suppress-warnings", false, "Do not log warnings"),
+	}
+}
+
+// config builds an escposimg.Config from the parsed flags
+func (f *configFlags) config() (*escposimg.Config, error) {
+	// Parse dithering algorithm
+	ditheringType, err := parseDitheringAlgo(*f.ditheringAlgo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the per-plane algorithms of two-color output
+	blackAlgo, err := parseOptionalDitheringAlgo(*f.blackDithering)
+	if err != nil {
+		return nil, err
+	}
+	redAlgo, err := parseOptionalDitheringAlgo(*f.redDithering)
+	if err != nil {
+		return nil, err
+	}
+
+	// A negative number of feed lines keeps the default of the generator
+	var feedLines *int
+	if *f.feedLines >= 0 {
+		feedLines = f.feedLines
+	}
+
+	// Parse scale filter
+	scaleFilter, err := parseScaleFilter(*f.scaleFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse height rounding
+	heightRounding, err := parseHeightRounding(*f.heightRounding)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse print mode
+	printModeType, err := parsePrintMode(*f.printMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse dialect
+	dialect, err := parseDialect(*f.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse raster scale
+	rasterScale, err := parseRasterScale(*f.rasterScale)
+	if err != nil {
+		return nil, err
+	}
+	separator, err := escposimg.ParseSeparatorStyle(*f.separator)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse alignment
+	alignment, err := parseAlignment(*f.align)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse barcode text position
+	barcodeHRI, err := parseHRIPosition(*f.barcodeHRI)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse cut type
+	cutType, err := parseCutType(*f.cutType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate bit image mode
+	if *f.bitImageMode != 0 && *f.bitImageMode != 32 && *f.bitImageMode != 33 {
+		return nil, fmt.Errorf("invalid bit image mode %d (expected 0, 32 or 33)", *f.bitImageMode)
+	}
+
+	// Validate threshold
+	if *f.threshold > 255 {
+		return nil, fmt.Errorf("invalid threshold %d (expected 0-255)", *f.threshold)
+	}
+
+	// Validate motion units
+	for _, unit := range []int{*f.motionUnitX, *f.motionUnitY} {
+		if unit < 0 || unit > 255 {
+			return nil, fmt.Errorf("invalid motion unit %d (expected 0-255)", unit)
+		}
+	}
+
+	// Validate gamma
+	if *f.gamma < 0 {
+		return nil, fmt.Errorf("invalid gamma %g (expected a positive value or 0)", *f.gamma)
+	}
+
+	config := &escposimg.Config{
+		PaperWidthMM:        *f.paperWidth,
+		WidthDots:           *f.widthDots,
+		PageWidthDots:       *f.pageWidth,
+		OffsetXPx:           *f.offsetX,
+		Alignment:           alignment,
+		DPI:                 *f.dpi,
+		DPIX:                *f.dpiX,
+		DPIY:                *f.dpiY,
+		Rotation:            *f.rotate,
+		RotateDegrees:       *f.rotateDegrees,
+		ScaleFilter:         scaleFilter,
+		HeightRounding:      heightRounding,
+		DitheringAlgo:       ditheringType,
+		AccurateGray:        *f.accurateGray,
+		Gamma:               *f.gamma,
+		Invert:              *f.invert,
+		Serpentine:          *f.serpentine,
+		DiffusionScale:      *f.diffusionScale,
+		BayerSize:           *f.bayerSize,
+		Threshold:           uint8(*f.threshold),
+		PrintMode:           printModeType,
+		BitImageMode:        byte(*f.bitImageMode),
+		Dialect:             dialect,
+		PreserveBuffer:      *f.preserveBuffer,
+		RasterScale:         rasterScale,
+		Smoothing:           *f.smoothing,
+		MotionUnitX:         *f.motionUnitX,
+		MotionUnitY:         *f.motionUnitY,
+		NoScale:             *f.noScale,
+		TransferMirror:      *f.transfer,
+		FlipHorizontal:      *f.flipH,
+		FlipVertical:        *f.flipV,
+		RedMaskPath:         *f.redMask,
+		BlackAlgo:           blackAlgo,
+		RedAlgo:             redAlgo,
+		TopRule:             *f.topRule,
+		BottomRule:          *f.bottomRule,
+		RuleHeightPx:        *f.ruleHeight,
+		SeparatorArt:        separator,
+		DebugOutput:         *f.debugOutput,
+		DebugImagePath:      *f.debugImagePath,
+		DebugText:           *f.debugText,
+		BarcodeData:         *f.barcodeData,
+		BarcodeType:         escposimg.BarcodeType(*f.barcodeType),
+		BarcodeHeightDots:   *f.barcodeHeight,
+		BarcodeHRI:          barcodeHRI,
+		BarcodeMetaKey:      *f.barcodeMetaKey,
+		ReverseVideo:        *f.reverseVideo,
+		CutPaper:            *f.cutPaper,
+		CutType:             cutType,
+		NoCutter:            *f.noCutter,
+		TearLine:            *f.tearLine,
+		SkipFinalFeed:       *f.skipFinalFeed,
+		FeedLinesBeforeCut:  feedLines,
+		FileComment:         *f.fileComment,
+		MaxOutputBytes:      *f.maxBytes,
+		MaxProcessingTime:   *f.maxTime,
+		InitDelay:           *f.initDelay,
+		TileHeightPx:        *f.tileHeight,
+		RegistrationMarks:   *f.regMarks,
+		TrailingBlankLabels: *f.blankLabels,
+		LabelHeightMM:       *f.labelHeight,
+		Strict:              *f.strict,
+		SuppressWarnings:    *f.quiet,
+	}
+
+	config.PaddingPx = escposimg.Padding{
+		Top:    *f.padTop,
+		Right:  *f.padRight,
+		Bottom: *f.padBottom,
+		Left:   *f.padLeft,
+	}
+
+	return config, nil
+}
+
+// outputFlags holds the flags selecting an output method
+type outputFlags struct {
+	method       *string
+	networkAddr  *string
+	netAttempts  *int
+	netDelay     *time.Duration
+	filePath     *string
+	devicePath   *string
+	httpURL      *string
+	httpType     *string
+	httpAuth     *string
+	printerName  *string
+	serialDevice *string
+	serialChunk  *int
+	serialDelay  *time.Duration
+	xonXoff      *bool
+}
+
+// addOutputFlags registers the output flags on a flag set
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	return &outputFlags{
+		method:       fs.String("output", "stdout", "Output method (stdout, network, file, device, serial, http, winspool, hex, base64)"),
+		networkAddr:  fs.String("network-addr", "", "Network address for network output (e.g., 192.168.1.100:9100)"),
+		netAttempts:  fs.Int("network-attempts", 1, "Attempts to connect to and write to the network printer, reconnecting after a failed write"),
+		netDelay:     fs.Duration("network-retry-delay", time.Second, "Pause between network attempts"),
+		filePath:     fs.String("file-path", "", "File path for file output"),
+		devicePath:   fs.String("device-path", "", "Printer device for device output (e.g., /dev/usb/lp0)"),
+		httpURL:      fs.String("http-url", "", "URL of the print server for http output, which receives the data in a POST request"),
+		httpType:     fs.String("http-content-type", "application/octet-stream", "Content type of the http output request"),
+		httpAuth:     fs.String("http-auth", "", "Authorization header of the http output request (e.g., \"Bearer <token>\")"),
+		printerName:  fs.String("printer-name", "", "Name of the Windows printer for winspool output"),
+		serialDevice: fs.String("serial-device", "", "Serial port for serial output (e.g., /dev/ttyUSB0), configured beforehand with stty"),
+		serialChunk:  fs.Int("serial-chunk", 0, "Bytes written to the serial port at once (0 writes all data at once, or 64 bytes with -xonxoff)"),
+		serialDelay:  fs.Duration("serial-delay", 0, "Pause after each serial chunk (e.g. 2ms)"),
+		xonXoff:      fs.Bool("xonxoff", false, "Honor XON/XOFF software flow control on the serial port"),
+	}
+}
+
+// create opens the output method selected by the flags
+func (f *outputFlags) create() (escposimg.OutputMethod, error) {
+	output, err := createOutputMethod(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output method: %w", err)
+	}
+	return output, nil
+}
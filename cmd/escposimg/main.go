@@ -1,7 +1,8 @@
 package main
 
 import (
-	"flag"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"os"
@@ -10,101 +11,90 @@ import (
 	"github.com/72nd/escposimg"
 )
 
+// subcommand describes a CLI subcommand
+type subcommand struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+// subcommands lists all available subcommands in the order shown in the usage text
+var subcommands = []subcommand{
+	{"process", "Process an image and send ESC/POS commands to an output (default)", runProcess},
+	{"test-pattern", "Send a checkerboard test pattern to an output", runTestPattern},
+	{"grid", "Send a calibration grid with labeled dimensions to an output", runGrid},
+	{"gradient", "Send a dithered gray gradient for threshold and density calibration", runGradient},
+	{"compare", "Print an image once per dithering algorithm for comparison on paper", runCompare},
+	{"inspect", "Dump the commands contained in an ESC/POS file", runInspect},
+	{"diff", "Show the command-level differences between two ESC/POS files", runDiff},
+	{"preview", "Dither an image and save the result as PNG", runPreview},
+	{"manifest", "Print a multi-part receipt described by a JSON manifest", runManifest},
+}
+
 func main() {
-	// Define command line flags
-	var (
-		imagePath      = flag.String("image", "", "Path to the image file (required)")
-		paperWidth     = flag.Int("paper-width", 80, "Paper width in millimeters")
-		dpi            = flag.Int("dpi", 203, "Printer DPI")
-		ditheringAlgo  = flag.String("dithering", "floyd-steinberg", "Dithering algorithm (floyd-steinberg, atkinson, threshold, bayer, burkes, sierra-lite, jarvis-judice-ninke, shadura)")
-		printMode      = flag.String("print-mode", "raster", "ESC/POS print mode (raster, bit-image)")
-		debugOutput    = flag.Bool("debug-output", false, "Save dithered image for debugging")
-		debugImagePath = flag.String("debug-image", "debug_output.png", "Path to save debug image")
-		debugText      = flag.String("debug-text", "", "Optional debug text to print before image")
-		cutPaper       = flag.Bool("cut", false, "Send paper cut command after printing")
-		outputMethod   = flag.String("output", "stdout", "Output method (stdout, network, file)")
-		networkAddr    = flag.String("network-addr", "", "Network address for network output (e.g., 192.168.1.100:9100)")
-		filePath       = flag.String("file-path", "", "File path for file output")
-		verbose        = flag.Bool("verbose", false, "Enable verbose logging")
-		version        = flag.Bool("version", false, "Show version information")
-	)
-
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "escposimg processes images for ESC/POS thermal printers.\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  %s -image photo.jpg\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -image photo.jpg -output network -network-addr 192.168.1.100:9100\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -image photo.jpg -dithering threshold -debug-output\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -image photo.jpg -print-mode bit-image\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -image photo.jpg -print-mode raster -dithering atkinson\n", os.Args[0])
-	}
-
-	flag.Parse()
-
-	// Show version and exit
-	if *version {
-		fmt.Printf("escposimg version %s\n", escposimg.Version())
-		return
-	}
+	args := os.Args[1:]
 
-	// Set up logging
-	logLevel := slog.LevelInfo
-	if *verbose {
-		logLevel = slog.LevelDebug
+	// Without a subcommand the flags are handed to "process" so that
+	// invocations like `escposimg -image photo.jpg` keep working.
+	name := "process"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
-	slog.SetDefault(logger)
 
-	// Validate required arguments
-	if *imagePath == "" {
-		fmt.Fprintf(os.Stderr, "Error: -image is required\n\n")
-		flag.Usage()
-		os.Exit(1)
+	if name == "help" {
+		usage()
+		return
 	}
 
-	// Parse dithering algorithm
-	ditheringType, err := parseDitheringAlgo(*ditheringAlgo)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	for _, cmd := range subcommands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	// Parse print mode
-	printModeType, err := parsePrintMode(*printMode)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	fmt.Fprintf(os.Stderr, "Error: unknown subcommand %q\n\n", name)
+	usage()
+	os.Exit(1)
+}
 
-	// Create configuration
-	config := &escposimg.Config{
-		PaperWidthMM:   *paperWidth,
-		DPI:            *dpi,
-		DitheringAlgo:  ditheringType,
-		PrintMode:      printModeType,
-		DebugOutput:    *debugOutput,
-		DebugImagePath: *debugImagePath,
-		DebugText:      *debugText,
-		CutPaper:       *cutPaper,
+// usage prints the top-level help text
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "escposimg processes images for ESC/POS thermal printers.\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.description)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the options of a command.\n", os.Args[0])
+}
 
-	// Create output method
-	output, err := createOutputMethod(*outputMethod, *networkAddr, *filePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating output method: %v\n", err)
-		os.Exit(1)
+// setupLogging configures logging to write to stderr, keeping stdout free for
+// printer data when using the stdout output method
+func setupLogging(verbose bool) {
+	logLevel := slog.LevelInfo
+	if verbose {
+		logLevel = slog.LevelDebug
 	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(logger)
+	escposimg.SetLogger(logger)
+}
 
-	// Process the image
-	if err := escposimg.ProcessImage(*imagePath, config, output); err != nil {
-		fmt.Fprintf(os.Stderr, "Error processing image: %v\n", err)
-		os.Exit(1)
+// parseOptionalDitheringAlgo converts string to DitheringType, returning nil for an empty string
+func parseOptionalDitheringAlgo(algo string) (*escposimg.DitheringType, error) {
+	if algo == "" {
+		return nil, nil
 	}
-
-	slog.Info("Image processed successfully")
+	ditheringType, err := parseDitheringAlgo(algo)
+	if err != nil {
+		return nil, err
+	}
+	return &ditheringType, nil
 }
 
 // parseDitheringAlgo converts string to DitheringType
@@ -126,11 +116,49 @@ func parseDitheringAlgo(algo string) (escposimg.DitheringType, error) {
 		return escposimg.DitheringJarvisJudiceNinke, nil
 	case "shadura":
 		return escposimg.DitheringShadura, nil
+	case "stucki":
+		return escposimg.DitheringStucki, nil
+	case "sierra":
+		return escposimg.DitheringSierra, nil
+	case "halftone":
+		return escposimg.DitheringHalftone, nil
+	case "adaptive":
+		return escposimg.DitheringAdaptive, nil
 	default:
 		return 0, fmt.Errorf("unknown dithering algorithm: %s", algo)
 	}
 }
 
+// parseScaleFilter converts string to ScaleFilter
+func parseScaleFilter(filter string) (escposimg.ScaleFilter, error) {
+	switch strings.ToLower(filter) {
+	case "lanczos3":
+		return escposimg.ScaleFilterLanczos3, nil
+	case "bilinear":
+		return escposimg.ScaleFilterBilinear, nil
+	case "nearest":
+		return escposimg.ScaleFilterNearestNeighbor, nil
+	case "area":
+		return escposimg.ScaleFilterAreaAverage, nil
+	default:
+		return 0, fmt.Errorf("unknown scale filter: %s (supported: lanczos3, bilinear, nearest, area)", filter)
+	}
+}
+
+// parseHeightRounding converts string to HeightRounding
+func parseHeightRounding(rounding string) (escposimg.HeightRounding, error) {
+	switch strings.ToLower(rounding) {
+	case "round":
+		return escposimg.HeightRoundNearest, nil
+	case "floor":
+		return escposimg.HeightRoundDown, nil
+	case "ceil":
+		return escposimg.HeightRoundUp, nil
+	default:
+		return 0, fmt.Errorf("unknown height rounding: %s (supported: round, floor, ceil)", rounding)
+	}
+}
+
 // parsePrintMode converts string to PrintMode
 func parsePrintMode(mode string) (escposimg.PrintMode, error) {
 	switch strings.ToLower(mode) {
@@ -143,8 +171,81 @@ func parsePrintMode(mode string) (escposimg.PrintMode, error) {
 	}
 }
 
+// parseDialect converts string to Dialect
+func parseDialect(dialect string) (escposimg.Dialect, error) {
+	switch strings.ToLower(dialect) {
+	case "epson":
+		return escposimg.DialectEpson, nil
+	case "star":
+		return escposimg.DialectStar, nil
+	default:
+		return 0, fmt.Errorf("unknown dialect: %s (supported: epson, star)", dialect)
+	}
+}
+
+// parseRasterScale converts string to RasterScale
+func parseRasterScale(scale string) (escposimg.RasterScale, error) {
+	switch strings.ToLower(scale) {
+	case "normal":
+		return escposimg.RasterScaleNormal, nil
+	case "double-width":
+		return escposimg.RasterScaleDoubleWidth, nil
+	case "double-height":
+		return escposimg.RasterScaleDoubleHeight, nil
+	case "quadruple":
+		return escposimg.RasterScaleQuadruple, nil
+	default:
+		return 0, fmt.Errorf("unknown raster scale: %s (supported: normal, double-width, double-height, quadruple)", scale)
+	}
+}
+
+// parseAlignment converts string to Alignment
+func parseAlignment(align string) (escposimg.Alignment, error) {
+	switch strings.ToLower(align) {
+	case "left":
+		return escposimg.AlignLeft, nil
+	case "center":
+		return escposimg.AlignCenter, nil
+	case "right":
+		return escposimg.AlignRight, nil
+	default:
+		return 0, fmt.Errorf("unknown alignment: %s (supported: left, center, right)", align)
+	}
+}
+
+// parseHRIPosition converts string to HRIPosition
+func parseHRIPosition(position string) (escposimg.HRIPosition, error) {
+	switch strings.ToLower(position) {
+	case "below":
+		return escposimg.HRIBelow, nil
+	case "above":
+		return escposimg.HRIAbove, nil
+	case "both":
+		return escposimg.HRIBoth, nil
+	case "none":
+		return escposimg.HRINone, nil
+	default:
+		return 0, fmt.Errorf("unknown barcode text position: %s (supported: below, above, both, none)", position)
+	}
+}
+
+// parseCutType converts string to CutType
+func parseCutType(cut string) (escposimg.CutType, error) {
+	switch strings.ToLower(cut) {
+	case "", "none":
+		return escposimg.CutNone, nil
+	case "partial":
+		return escposimg.CutPartial, nil
+	case "full":
+		return escposimg.CutFull, nil
+	default:
+		return 0, fmt.Errorf("unknown cut type: %s (supported: none, partial, full)", cut)
+	}
+}
+
 // createOutputMethod creates the appropriate output method based on the flag
-func createOutputMethod(method, networkAddr, filePath string) (escposimg.OutputMethod, error) {
+func createOutputMethod(f *outputFlags) (escposimg.OutputMethod, error) {
+	method, networkAddr, filePath := *f.method, *f.networkAddr, *f.filePath
 	switch strings.ToLower(method) {
 	case "stdout":
 		return escposimg.NewStdoutOutput(), nil
@@ -152,13 +253,69 @@ func createOutputMethod(method, networkAddr, filePath string) (escposimg.OutputM
 		if networkAddr == "" {
 			return nil, fmt.Errorf("network address is required for network output")
 		}
-		return escposimg.NewNetworkOutput(networkAddr)
+		return escposimg.NewNetworkOutputWithConfig(networkAddr, escposimg.NetworkOutputConfig{
+			MaxAttempts: *f.netAttempts,
+			RetryDelay:  *f.netDelay,
+		})
 	case "file":
 		if filePath == "" {
 			return nil, fmt.Errorf("file path is required for file output")
 		}
 		return escposimg.NewFileOutput(filePath)
+	case "device":
+		if *f.devicePath == "" {
+			return nil, fmt.Errorf("device path is required for device output")
+		}
+		return escposimg.NewDeviceOutput(*f.devicePath)
+	case "serial":
+		if *f.serialDevice == "" {
+			return nil, fmt.Errorf("serial device is required for serial output")
+		}
+		return escposimg.NewSerialOutput(*f.serialDevice, escposimg.SerialConfig{
+			ChunkSize:           *f.serialChunk,
+			ChunkDelay:          *f.serialDelay,
+			SoftwareFlowControl: *f.xonXoff,
+		})
+	case "http":
+		if *f.httpURL == "" {
+			return nil, fmt.Errorf("URL is required for http output")
+		}
+		output, err := escposimg.NewHTTPOutput(*f.httpURL)
+		if err != nil {
+			return nil, err
+		}
+		output.ContentType = *f.httpType
+		output.Authorization = *f.httpAuth
+		return output, nil
+	case "winspool":
+		if *f.printerName == "" {
+			return nil, fmt.Errorf("printer name is required for winspool output")
+		}
+		return newWinspoolOutput(*f.printerName)
+	case "hex":
+		return &encodedOutput{encode: hex.EncodeToString}, nil
+	case "base64":
+		return &encodedOutput{encode: base64.StdEncoding.EncodeToString}, nil
 	default:
 		return nil, fmt.Errorf("unknown output method: %s", method)
 	}
 }
+
+// encodedOutput collects the printer data and prints it as an encoded string to
+// stdout on Close, for pasting into debugging tools
+type encodedOutput struct {
+	encode func([]byte) string
+	data   []byte
+}
+
+// Write buffers the data until Close
+func (e *encodedOutput) Write(data []byte) error {
+	e.data = append(e.data, data...)
+	return nil
+}
+
+// Close prints the encoded data followed by a newline
+func (e *encodedOutput) Close() error {
+	_, err := fmt.Println(e.encode(e.data))
+	return err
+}
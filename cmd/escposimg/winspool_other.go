@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+
+	"github.com/72nd/escposimg"
+)
+
+// newWinspoolOutput reports that the Windows print spooler is not available
+func newWinspoolOutput(string) (escposimg.OutputMethod, error) {
+	return nil, errors.New("winspool output is only available on Windows")
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "github.com/72nd/escposimg"
+
+// newWinspoolOutput opens a printer installed in Windows for a RAW print job
+func newWinspoolOutput(printerName string) (escposimg.OutputMethod, error) {
+	return escposimg.NewWindowsSpoolOutput(printerName)
+}
@@ -0,0 +1,113 @@
+package escposimg
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+)
+
+// CompareDithering scales an image as configured and dithers it with every
+// algorithm, returning the results keyed by algorithm. config.DitheringAlgo is
+// ignored, while the configured threshold applies to every algorithm.
+func CompareDithering(imagePath string, config *Config) (map[DitheringType]image.Image, error) {
+	return compareDithering(imagePath, DitheringTypes(), config)
+}
+
+// compareDithering loads and scales an image once and dithers it with each of
+// the given algorithms
+func compareDithering(imagePath string, algos []DitheringType, config *Config) (map[DitheringType]image.Image, error) {
+	img, err := LoadImage(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
+	}
+	source, _, err := layoutImage(img, config, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[DitheringType]image.Image, len(algos))
+	for _, algo := range algos {
+		algoConfig := *config
+		algoConfig.DitheringAlgo = algo
+
+		dithered, err := ApplyDitheringConfig(source, &algoConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply %s dithering: %w", algo, err)
+		}
+		results[algo] = dithered
+	}
+	return results, nil
+}
+
+// CompareDitheringZip dithers an image with every algorithm (see CompareDithering)
+// and writes the results as a zip archive to w, with one PNG named after each
+// algorithm, e.g. "floyd-steinberg.png"
+func CompareDitheringZip(imagePath string, config *Config, w io.Writer) error {
+	results, err := CompareDithering(imagePath, config)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, algo := range DitheringTypes() {
+		entry, err := archive.Create(algo.String() + ".png")
+		if err != nil {
+			return fmt.Errorf("failed to create zip entry: %w", err)
+		}
+		if err := png.Encode(entry, results[algo]); err != nil {
+			return fmt.Errorf("failed to encode %s image: %w", algo, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+
+	logger().Debug("Dithering comparison archive written", "algorithms", len(results))
+	return nil
+}
+
+// GenerateAlgorithmComparison generates one receipt printing the same image once per
+// dithering algorithm, each preceded by a text label with the algorithm name, so the
+// algorithms can be compared on the actual printer. All algorithms are printed if
+// algos is empty.
+//
+// The image is scaled once as configured; config.DitheringAlgo is ignored, while
+// the configured threshold applies to every algorithm.
+func GenerateAlgorithmComparison(imagePath string, algos []DitheringType, config *Config) ([]byte, error) {
+	if len(algos) == 0 {
+		algos = DitheringTypes()
+	}
+
+	results, err := compareDithering(imagePath, algos, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	for i, algo := range algos {
+		if i > 0 {
+			buf.WriteByte(LF)
+		}
+		writeTextLine(&buf, algo.String(), config)
+		if err := writeImage(&buf, results[algo], config); err != nil {
+			return nil, err
+		}
+		logger().Debug("Added comparison image", "algorithm", algo.String())
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	// Feed and cut if requested
+	writeFinalFeed(&buf, 3, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Algorithm comparison generated", "algorithms", len(algos), "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,261 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffKind classifies a difference between two ESC/POS streams
+type DiffKind int
+
+const (
+	// DiffChanged is a command present in both streams with different parameters or data
+	DiffChanged DiffKind = iota
+	// DiffAdded is a command only present in the second stream
+	DiffAdded
+	// DiffRemoved is a command only present in the first stream
+	DiffRemoved
+)
+
+// String returns the string representation of the diff kind
+func (k DiffKind) String() string {
+	switch k {
+	case DiffChanged:
+		return "changed"
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// CommandDiff describes a single command-level difference found by DiffESCPOS
+type CommandDiff struct {
+	Kind DiffKind
+
+	// Command of the first stream (zero for DiffAdded)
+	A Command
+
+	// Command of the second stream (zero for DiffRemoved)
+	B Command
+
+	// Human-readable summary of the difference, e.g. "raster height 400 vs 401"
+	Description string
+}
+
+// String returns a human-readable description of the difference
+func (d CommandDiff) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %08x  %s", d.B.Offset, d.Description)
+	case DiffRemoved:
+		return fmt.Sprintf("- %08x  %s", d.A.Offset, d.Description)
+	default:
+		return fmt.Sprintf("~ %08x/%08x  %s", d.A.Offset, d.B.Offset, d.Description)
+	}
+}
+
+// DiffESCPOS compares two ESC/POS byte streams command by command and returns
+// their differences in stream order; identical streams yield no differences.
+//
+// Both streams are decoded with ParseESCPOS and aligned on commands of the same
+// name, preferring identical commands. Aligned commands whose parameters or data
+// differ are reported as changed, unaligned ones as added or removed. This makes
+// regression failures readable as e.g. "raster height 400 vs 401" or
+// "extra ESC J (feed 24 dots)" instead of a wall of differing bytes.
+func DiffESCPOS(a, b []byte) ([]CommandDiff, error) {
+	cmdsA, err := ParseESCPOS(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse first stream: %w", err)
+	}
+	cmdsB, err := ParseESCPOS(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse second stream: %w", err)
+	}
+
+	var diffs []CommandDiff
+	i, j := 0, 0
+	for _, pair := range alignCommands(cmdsA, cmdsB) {
+		for ; i < pair[0]; i++ {
+			diffs = append(diffs, CommandDiff{Kind: DiffRemoved, A: cmdsA[i], Description: "missing " + commandSummary(cmdsA[i])})
+		}
+		for ; j < pair[1]; j++ {
+			diffs = append(diffs, CommandDiff{Kind: DiffAdded, B: cmdsB[j], Description: "extra " + commandSummary(cmdsB[j])})
+		}
+		if !sameCommand(cmdsA[i], cmdsB[j]) {
+			diffs = append(diffs, CommandDiff{Kind: DiffChanged, A: cmdsA[i], B: cmdsB[j], Description: describeChange(cmdsA[i], cmdsB[j])})
+		}
+		i, j = i+1, j+1
+	}
+	for ; i < len(cmdsA); i++ {
+		diffs = append(diffs, CommandDiff{Kind: DiffRemoved, A: cmdsA[i], Description: "missing " + commandSummary(cmdsA[i])})
+	}
+	for ; j < len(cmdsB); j++ {
+		diffs = append(diffs, CommandDiff{Kind: DiffAdded, B: cmdsB[j], Description: "extra " + commandSummary(cmdsB[j])})
+	}
+
+	return diffs, nil
+}
+
+// alignCommands returns the index pairs of the best alignment of a and b, found
+// as a weighted longest common subsequence where commands of the same name score
+// one point and identical commands two.
+//
+// Identical leading and trailing commands are aligned directly; the rest is
+// split recursively (Hirschberg's algorithm), so memory stays linear in the
+// number of commands even for long streams.
+func alignCommands(a, b []Command) [][2]int {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && sameCommand(a[prefix], b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && sameCommand(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	var pairs [][2]int
+	for i := 0; i < prefix; i++ {
+		pairs = append(pairs, [2]int{i, i})
+	}
+	pairs = alignRange(pairs, a, b, prefix, len(a)-suffix, prefix, len(b)-suffix)
+	for k := suffix; k > 0; k-- {
+		pairs = append(pairs, [2]int{len(a) - k, len(b) - k})
+	}
+	return pairs
+}
+
+// alignRange appends the best alignment of a[aLo:aHi] and b[bLo:bHi] to pairs by
+// splitting a in half and b where the scores of both halves add up best
+func alignRange(pairs [][2]int, a, b []Command, aLo, aHi, bLo, bHi int) [][2]int {
+	if aLo == aHi || bLo == bHi {
+		return pairs
+	}
+	if aHi-aLo == 1 {
+		bestJ, bestScore := -1, 0
+		for j := bLo; j < bHi; j++ {
+			if s := alignScore(a[aLo], b[j]); s > bestScore {
+				bestJ, bestScore = j, s
+			}
+		}
+		if bestJ >= 0 {
+			pairs = append(pairs, [2]int{aLo, bestJ})
+		}
+		return pairs
+	}
+
+	mid := (aLo + aHi) / 2
+	forward := alignScores(a[aLo:mid], b[bLo:bHi], false)
+	backward := alignScores(a[mid:aHi], b[bLo:bHi], true)
+	split, best := 0, -1
+	for k := range forward {
+		if s := forward[k] + backward[k]; s > best {
+			split, best = k, s
+		}
+	}
+	pairs = alignRange(pairs, a, b, aLo, mid, bLo, bLo+split)
+	return alignRange(pairs, a, b, mid, aHi, bLo+split, bHi)
+}
+
+// alignScores returns the best alignment score of a with each prefix b[:k] of b,
+// or with each suffix b[k:] if reverse is set, keeping two rows of the score table
+func alignScores(a, b []Command, reverse bool) []int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	if !reverse {
+		for i := range a {
+			for k := 1; k <= len(b); k++ {
+				cur[k] = max(prev[k], cur[k-1])
+				if s := alignScore(a[i], b[k-1]); s > 0 {
+					cur[k] = max(cur[k], prev[k-1]+s)
+				}
+			}
+			prev, cur = cur, prev
+		}
+		return prev
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for k := len(b) - 1; k >= 0; k-- {
+			cur[k] = max(prev[k], cur[k+1])
+			if s := alignScore(a[i], b[k]); s > 0 {
+				cur[k] = max(cur[k], prev[k+1]+s)
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// alignScore scores aligning two commands: two for identical commands, one for
+// commands of the same name and zero otherwise
+func alignScore(x, y Command) int {
+	switch {
+	case x.Name != y.Name:
+		return 0
+	case sameCommand(x, y):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// sameCommand reports whether two commands are identical apart from their offset
+func sameCommand(a, b Command) bool {
+	return a.Name == b.Name && bytes.Equal(a.Params, b.Params) && bytes.Equal(a.Data, b.Data)
+}
+
+// commandSummary names a command together with its decoded parameters
+func commandSummary(c Command) string {
+	if c.Name == "TEXT" {
+		return fmt.Sprintf("TEXT %q", c.Data)
+	}
+	if detail := c.detail(); detail != "" {
+		return fmt.Sprintf("%s (%s)", c.Name, detail)
+	}
+	return c.Name
+}
+
+// describeChange summarizes how two commands of the same name differ
+func describeChange(a, b Command) string {
+	if a.Name == "GS v 0" {
+		var changes []string
+		if a.Params[0] != b.Params[0] {
+			changes = append(changes, fmt.Sprintf("raster mode %d vs %d", a.Params[0], b.Params[0]))
+		}
+		if wa, wb := int(le16(a.Params[1:3]))*8, int(le16(b.Params[1:3]))*8; wa != wb {
+			changes = append(changes, fmt.Sprintf("raster width %d vs %d", wa, wb))
+		}
+		if ha, hb := le16(a.Params[3:5]), le16(b.Params[3:5]); ha != hb {
+			changes = append(changes, fmt.Sprintf("raster height %d vs %d", ha, hb))
+		}
+		if len(changes) > 0 {
+			return strings.Join(changes, ", ")
+		}
+	}
+
+	switch {
+	case a.Name == "TEXT":
+		return fmt.Sprintf("text %q vs %q", a.Data, b.Data)
+	case a.detail() != b.detail():
+		return fmt.Sprintf("%s: %s vs %s", a.Name, a.detail(), b.detail())
+	case !bytes.Equal(a.Params, b.Params):
+		return fmt.Sprintf("%s: params % x vs % x", a.Name, a.Params, b.Params)
+	case len(a.Data) != len(b.Data):
+		return fmt.Sprintf("%s: data %d vs %d bytes", a.Name, len(a.Data), len(b.Data))
+	default:
+		return fmt.Sprintf("%s: data differs from byte %d", a.Name, firstDifference(a.Data, b.Data))
+	}
+}
+
+// firstDifference returns the index of the first differing byte of two equally long slices
+func firstDifference(a, b []byte) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
@@ -0,0 +1,108 @@
+package escposimg
+
+import (
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDiffESCPOS(t *testing.T) {
+	base := []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 2, 0, 0xFF, 0x00, LF, LF}
+
+	tests := []struct {
+		name  string
+		b     []byte
+		kinds []DiffKind
+		want  string
+	}{
+		{"identical", base, nil, ""},
+		{"raster height", []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 1, 0, 0xFF, LF, LF}, []DiffKind{DiffChanged}, "raster height 2 vs 1"},
+		{"extra feed", []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 2, 0, 0xFF, 0x00, ESC, 'J', 24, LF, LF}, []DiffKind{DiffAdded}, "extra ESC J"},
+		{"missing line feed", []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 2, 0, 0xFF, 0x00, LF}, []DiffKind{DiffRemoved}, "missing LF"},
+		{"raster data", []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 2, 0, 0xFF, 0x01, LF, LF}, []DiffKind{DiffChanged}, "data differs from byte 1"},
+	}
+	for _, tt := range tests {
+		diffs, err := DiffESCPOS(base, tt.b)
+		if err != nil {
+			t.Fatalf("%s: DiffESCPOS() error = %v", tt.name, err)
+		}
+		if len(diffs) != len(tt.kinds) {
+			t.Fatalf("%s: got %d differences %v, want %d", tt.name, len(diffs), diffs, len(tt.kinds))
+		}
+		for i, d := range diffs {
+			if d.Kind != tt.kinds[i] {
+				t.Errorf("%s: difference %d is %s, want %s", tt.name, i, d.Kind, tt.kinds[i])
+			}
+			if !strings.Contains(d.Description, tt.want) {
+				t.Errorf("%s: description %q does not contain %q", tt.name, d.Description, tt.want)
+			}
+		}
+	}
+}
+
+// quadraticAlignScore computes the best alignment score with the full score table
+func quadraticAlignScore(a, b []Command) int {
+	best := make([][]int, len(a)+1)
+	for i := range best {
+		best[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			best[i][j] = max(best[i+1][j], best[i][j+1])
+			if s := alignScore(a[i], b[j]); s > 0 {
+				best[i][j] = max(best[i][j], best[i+1][j+1]+s)
+			}
+		}
+	}
+	return best[0][0]
+}
+
+func TestAlignCommandsOptimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomCommands := func() []Command {
+		cmds := make([]Command, rng.Intn(12))
+		for i := range cmds {
+			cmds[i] = Command{Name: []string{"LF", "ESC J", "TEXT"}[rng.Intn(3)], Params: []byte{byte(rng.Intn(2))}}
+		}
+		return cmds
+	}
+
+	for n := 0; n < 500; n++ {
+		a, b := randomCommands(), randomCommands()
+		pairs := alignCommands(a, b)
+		score := 0
+		for k, p := range pairs {
+			if k > 0 && (p[0] <= pairs[k-1][0] || p[1] <= pairs[k-1][1]) {
+				t.Fatalf("alignment %v is not increasing", pairs)
+			}
+			if alignScore(a[p[0]], b[p[1]]) == 0 {
+				t.Fatalf("alignment %v pairs commands of different names", pairs)
+			}
+			score += alignScore(a[p[0]], b[p[1]])
+		}
+		if want := quadraticAlignScore(a, b); score != want {
+			t.Fatalf("alignment score = %d, want %d for %v / %v", score, want, a, b)
+		}
+	}
+}
+
+func TestDiffESCPOSLongStreams(t *testing.T) {
+	// 5000 commands each would need a score table of 2.5*10^7 entries
+	a := []byte(strings.Repeat("x\n", 5000))
+	b := []byte(strings.Repeat("y\n", 5000))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diffs, err := DiffESCPOS(a, b)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("DiffESCPOS() error = %v", err)
+	}
+	if len(diffs) != 5000 {
+		t.Errorf("got %d differences, want 5000 changed texts", len(diffs))
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+		t.Errorf("DiffESCPOS() allocated %d bytes, want the alignment in linear space", alloc)
+	}
+}
@@ -1,36 +1,174 @@
 package escposimg
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/color"
-	"log/slog"
+	"math"
 )
 
 // ApplyDithering applies the specified dithering algorithm to the image
+// using the algorithm's default threshold.
+//
+// Images whose bounds do not start at (0,0), such as sub-images, are supported;
+// the returned image always has its origin at (0,0).
 func ApplyDithering(img image.Image, algo DitheringType) (image.Image, error) {
-	slog.Debug("Applying dithering algorithm", "algorithm", algo.String())
+	return applyDithering(img, algo, ditherParams{threshold: DefaultThreshold(algo)})
+}
+
+// ApplyDitheringConfig applies the dithering algorithm selected in the configuration,
+// honouring the configured threshold
+func ApplyDitheringConfig(img image.Image, config *Config) (image.Image, error) {
+	return applyDithering(img, config.DitheringAlgo, newDitherParams(config))
+}
+
+// withDitheringAlgo returns a copy of the configuration using the given algorithm
+func (c *Config) withDitheringAlgo(algo DitheringType) *Config {
+	algoConfig := *c
+	algoConfig.DitheringAlgo = algo
+	return &algoConfig
+}
+
+// applyDitheringContext applies the configured dithering algorithm like
+// ApplyDitheringConfig, stopping error diffusion early when ctx is done
+func applyDitheringContext(ctx context.Context, img image.Image, config *Config) (image.Image, error) {
+	p := newDitherParams(config)
+	p.ctx = ctx
+	return applyDithering(img, config.DitheringAlgo, p)
+}
+
+// DefaultThreshold returns the black/white threshold an algorithm uses when
+// Config.Threshold is not set. Ordered dithers use a slightly lower value to
+// compensate for the dot gain of thermal print heads.
+func DefaultThreshold(algo DitheringType) int {
+	switch algo {
+	case DitheringBayer, DitheringHalftone:
+		return 120
+	default:
+		return 128
+	}
+}
+
+// ditherProgressInterval is the number of rows between progress callbacks
+const ditherProgressInterval = 32
+
+// ditherParams holds the tunable parameters shared by the dithering algorithms
+type ditherParams struct {
+	// Gray level below which a pixel is considered black
+	threshold int
+
+	// Optional progress callback for error-diffusion loops
+	progress func(rowsDone, totalRows int)
+
+	// Optional context checked by error-diffusion loops to stop early
+	ctx context.Context
+
+	// Size of the Bayer matrix (see Config.BayerSize)
+	bayerSize int
+
+	// Convert to grayscale in linear light (see Config.AccurateGray)
+	accurateGray bool
+
+	// Gamma used to linearize RGB before grayscale conversion (see Config.Gamma)
+	gamma float64
+
+	// Reject unknown algorithms instead of falling back (see Config.StrictDithering)
+	strict bool
+
+	// Alternate the scan direction of error diffusion every row (see Config.Serpentine)
+	serpentine bool
+
+	// Factor applied to the diffused quantization error (see Config.DiffusionScale)
+	diffusionScale float64
+}
+
+// reportProgress invokes the progress callback every ditherProgressInterval rows
+// and after the last row. It is a no-op when no callback is set.
+func (p ditherParams) reportProgress(y, height int) {
+	if p.progress == nil {
+		return
+	}
+	rowsDone := y + 1
+	if rowsDone%ditherProgressInterval == 0 || rowsDone == height {
+		p.progress(rowsDone, height)
+	}
+}
+
+// checkCanceled returns the context error every ditherProgressInterval rows once
+// the context is done. It returns nil when no context is set.
+func (p ditherParams) checkCanceled(y int) error {
+	if p.ctx == nil || (y+1)%ditherProgressInterval != 0 {
+		return nil
+	}
+	return p.ctx.Err()
+}
+
+// newDitherParams derives the dithering parameters from a configuration
+func newDitherParams(config *Config) ditherParams {
+	threshold := DefaultThreshold(config.DitheringAlgo)
+	if config.Threshold != 0 {
+		threshold = int(config.Threshold)
+	}
+	return ditherParams{
+		threshold:      threshold,
+		progress:       config.DitherProgress,
+		accurateGray:   config.AccurateGray,
+		gamma:          config.Gamma,
+		strict:         config.StrictDithering,
+		serpentine:     config.Serpentine,
+		diffusionScale: config.DiffusionScale,
+		bayerSize:      config.BayerSize,
+	}
+}
+
+// applyDithering dispatches to the implementation of the given algorithm
+func applyDithering(img image.Image, algo DitheringType, p ditherParams) (image.Image, error) {
+	logger().Debug("Applying dithering algorithm", "algorithm", algo.String(), "threshold", p.threshold)
 
 	switch algo {
 	case DitheringFloydSteinberg:
-		return applyFloydSteinberg(img)
+		return applyFloydSteinberg(img, p)
 	case DitheringAtkinson:
-		return applyAtkinson(img)
+		return applyAtkinson(img, p)
 	case DitheringThreshold:
-		return applyThreshold(img)
+		return applyThreshold(img, p)
 	case DitheringBayer:
-		return applyBayer(img)
+		return applyBayer(img, p)
 	case DitheringBurkes:
-		return applyBurkes(img)
+		return applyBurkes(img, p)
 	case DitheringSierraLite:
-		return applySierraLite(img)
+		return applySierraLite(img, p)
 	case DitheringJarvisJudiceNinke:
-		return applyJarvisJudiceNinke(img)
+		return applyJarvisJudiceNinke(img, p)
 	case DitheringShadura:
-		return applyShadura(img)
+		return applyShadura(img, p)
+	case DitheringStucki:
+		return applyStucki(img, p)
+	case DitheringSierra:
+		return applySierra(img, p)
+	case DitheringHalftone:
+		return applyHalftone(img, p)
+	case DitheringAdaptive:
+		return applyAdaptive(img, p)
 	default:
-		slog.Warn("Unknown dithering algorithm, falling back to Floyd-Steinberg", "algorithm", algo)
-		return applyFloydSteinberg(img)
+		if p.strict {
+			return nil, fmt.Errorf("unknown dithering algorithm %d", algo)
+		}
+		logger().Warn("Unknown dithering algorithm, falling back to Floyd-Steinberg", "algorithm", algo)
+		return applyFloydSteinberg(img, p)
+	}
+}
+
+// grayscale converts an image to grayscale values using the configured conversion
+func (p ditherParams) grayscale(img image.Image) [][]uint8 {
+	if p.accurateGray {
+		return convertToLinearGrayscale(img)
+	}
+	if p.gamma > 0 && p.gamma != 1 {
+		return convertToGammaGrayscale(img, p.gamma)
 	}
+	return convertToGrayscale(img)
 }
 
 // convertToGrayscale converts an image to grayscale values
@@ -45,210 +183,220 @@ func convertToGrayscale(img image.Image) [][]uint8 {
 		for x := 0; x < width; x++ {
 			// Get pixel color and convert to grayscale using luminance formula
 			r, g, b, _ := img.At(x+bounds.Min.X, y+bounds.Min.Y).RGBA()
-			// Convert from 16-bit to 8-bit and apply luminance weights
-			grayValue := uint8((0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)))
+			// Convert from 16-bit to 8-bit and apply luminance weights, rounding so
+			// that gray input keeps its level (the weights sum to 1 only approximately)
+			grayValue := uint8(math.Round(0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)))
 			gray[y][x] = grayValue
 		}
 	}
 	return gray
 }
 
-// createMonochromeImage creates a black and white image from a boolean matrix
-func createMonochromeImage(pixels [][]bool, width, height int) image.Image {
-	img := image.NewGray(image.Rect(0, 0, width, height))
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			if pixels[y][x] {
-				img.SetGray(x, y, color.Gray{Y: 0}) // Black
-			} else {
-				img.SetGray(x, y, color.Gray{Y: 255}) // White
-			}
-		}
-	}
-	return img
-}
-
-// applyFloydSteinberg implements Floyd-Steinberg dithering
-func applyFloydSteinberg(img image.Image) (image.Image, error) {
+// convertToGammaGrayscale converts an image to grayscale values by decoding the
+// channels with a power-law gamma, applying the luminance weights of
+// convertToGrayscale to the linear values and encoding the result again
+func convertToGammaGrayscale(img image.Image, gamma float64) [][]uint8 {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	// Convert to grayscale
-	gray := convertToGrayscale(img)
+	var decode [256]float64
+	for i := range decode {
+		decode[i] = math.Pow(float64(i)/255, gamma)
+	}
 
-	// Convert to float64 for error diffusion calculations
-	pixels := make([][]float64, height)
+	gray := make([][]uint8, height)
 	for y := 0; y < height; y++ {
-		pixels[y] = make([]float64, width)
+		gray[y] = make([]uint8, width)
 		for x := 0; x < width; x++ {
-			pixels[y][x] = float64(gray[y][x])
+			r, g, b, _ := img.At(x+bounds.Min.X, y+bounds.Min.Y).RGBA()
+			luminance := 0.299*decode[r>>8] + 0.587*decode[g>>8] + 0.114*decode[b>>8]
+			gray[y][x] = uint8(math.Round(math.Pow(luminance, 1/gamma) * 255))
 		}
 	}
+	return gray
+}
 
-	result := make([][]bool, height)
-	for y := 0; y < height; y++ {
-		result[y] = make([]bool, width)
-	}
-
-	// Apply Floyd-Steinberg dithering
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			oldPixel := pixels[y][x]
-			var newPixel float64
-			var isBlack bool
-
-			if oldPixel < 128 {
-				newPixel = 0
-				isBlack = true
+// Lookup tables for sRGB decoding and encoding
+var (
+	srgbToLinear = func() (table [256]float64) {
+		for i := range table {
+			c := float64(i) / 255
+			if c <= 0.04045 {
+				table[i] = c / 12.92
 			} else {
-				newPixel = 255
-				isBlack = false
-			}
-
-			result[y][x] = isBlack
-			quantError := oldPixel - newPixel
-
-			// Distribute error to neighboring pixels
-			if x+1 < width {
-				pixels[y][x+1] += quantError * 7.0 / 16.0
+				table[i] = math.Pow((c+0.055)/1.055, 2.4)
 			}
-			if y+1 < height {
-				if x > 0 {
-					pixels[y+1][x-1] += quantError * 3.0 / 16.0
-				}
-				pixels[y+1][x] += quantError * 5.0 / 16.0
-				if x+1 < width {
-					pixels[y+1][x+1] += quantError * 1.0 / 16.0
-				}
+		}
+		return table
+	}()
+
+	linearToSRGB = func() (table [4096]uint8) {
+		for i := range table {
+			l := float64(i) / float64(len(table)-1)
+			var c float64
+			if l <= 0.0031308 {
+				c = l * 12.92
+			} else {
+				c = 1.055*math.Pow(l, 1/2.4) - 0.055
 			}
+			table[i] = uint8(math.Round(c * 255))
 		}
-	}
-
-	return createMonochromeImage(result, width, height), nil
-}
+		return table
+	}()
+)
 
-// applyAtkinson implements Atkinson dithering
-func applyAtkinson(img image.Image) (image.Image, error) {
+// convertToLinearGrayscale converts an image to grayscale values by linearizing
+// sRGB, applying the BT.709 luminance weights in linear light and encoding the
+// result as sRGB again
+func convertToLinearGrayscale(img image.Image) [][]uint8 {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	gray := convertToGrayscale(img)
-
-	pixels := make([][]float64, height)
+	gray := make([][]uint8, height)
 	for y := 0; y < height; y++ {
-		pixels[y] = make([]float64, width)
+		gray[y] = make([]uint8, width)
 		for x := 0; x < width; x++ {
-			pixels[y][x] = float64(gray[y][x])
+			r, g, b, _ := img.At(x+bounds.Min.X, y+bounds.Min.Y).RGBA()
+			luminance := 0.2126*srgbToLinear[r>>8] + 0.7152*srgbToLinear[g>>8] + 0.0722*srgbToLinear[b>>8]
+			gray[y][x] = linearToSRGB[int(luminance*float64(len(linearToSRGB)-1)+0.5)]
 		}
 	}
+	return gray
+}
 
-	result := make([][]bool, height)
-	for y := 0; y < height; y++ {
-		result[y] = make([]bool, width)
+// scanColumn returns the column visited at step i of row y and the horizontal
+// direction of the scan. Rows run left to right, or alternate direction in
+// serpentine mode so that the error diffusion kernel is mirrored on odd rows.
+func (p ditherParams) scanColumn(i, y, width int) (x, dir int) {
+	if p.serpentine && y%2 == 1 {
+		return width - 1 - i, -1
+	}
+	return i, 1
+}
+
+// diffuseError adds a share of the quantization error to a pixel, ignoring
+// positions outside the image
+func diffuseError(pixels [][]float64, x, y int, amount float64) {
+	if y < len(pixels) && x >= 0 && x < len(pixels[y]) {
+		pixels[y][x] += amount
 	}
+}
+
+// createMonochromeImage creates a black and white image from a boolean matrix.
+// The matrix is indexed relative to the source image's bounds.Min, so the result
+// always starts at (0,0) regardless of the origin of the image it came from.
+func createMonochromeImage(pixels [][]bool, width, height int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
 
-	// Apply Atkinson dithering
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			oldPixel := pixels[y][x]
-			var newPixel float64
-			var isBlack bool
-
-			if oldPixel < 128 {
-				newPixel = 0
-				isBlack = true
+			if pixels[y][x] {
+				img.SetGray(x, y, color.Gray{Y: 0}) // Black
 			} else {
-				newPixel = 255
-				isBlack = false
-			}
-
-			result[y][x] = isBlack
-			quantError := oldPixel - newPixel
-
-			// Atkinson dithering pattern (error distributed to 6 neighbors)
-			if x+1 < width {
-				pixels[y][x+1] += quantError / 8.0
-			}
-			if x+2 < width {
-				pixels[y][x+2] += quantError / 8.0
-			}
-			if y+1 < height {
-				if x > 0 {
-					pixels[y+1][x-1] += quantError / 8.0
-				}
-				pixels[y+1][x] += quantError / 8.0
-				if x+1 < width {
-					pixels[y+1][x+1] += quantError / 8.0
-				}
-			}
-			if y+2 < height {
-				pixels[y+2][x] += quantError / 8.0
+				img.SetGray(x, y, color.Gray{Y: 255}) // White
 			}
 		}
 	}
+	return img
+}
 
-	return createMonochromeImage(result, width, height), nil
+// DiffusionWeight is one neighbor of an error diffusion kernel: the pixel DX
+// columns right and DY rows below the current pixel receives Weight/Divisor of
+// its quantization error. Offsets are given for a left-to-right scan and mirrored
+// on right-to-left rows in serpentine mode.
+type DiffusionWeight struct {
+	DX, DY int
+	Weight float64
 }
 
-// applyThreshold implements simple threshold dithering
-func applyThreshold(img image.Image) (image.Image, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// DiffusionKernel describes how an error diffusion algorithm distributes the
+// quantization error of a pixel to its unprocessed neighbors
+type DiffusionKernel struct {
+	Weights []DiffusionWeight
+	Divisor float64
+}
 
-	gray := convertToGrayscale(img)
-	result := make([][]bool, height)
+// Error diffusion kernels of the built-in algorithms
+var (
+	floydSteinbergKernel = DiffusionKernel{Divisor: 16, Weights: []DiffusionWeight{
+		{1, 0, 7},
+		{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+	}}
+	atkinsonKernel = DiffusionKernel{Divisor: 8, Weights: []DiffusionWeight{
+		{1, 0, 1}, {2, 0, 1},
+		{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+		{0, 2, 1},
+	}}
+	burkesKernel = DiffusionKernel{Divisor: 32, Weights: []DiffusionWeight{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+	}}
+	sierraLiteKernel = DiffusionKernel{Divisor: 4, Weights: []DiffusionWeight{
+		{1, 0, 2},
+		{-1, 1, 1}, {0, 1, 1},
+	}}
+	sierraKernel = DiffusionKernel{Divisor: 32, Weights: []DiffusionWeight{
+		{1, 0, 5}, {2, 0, 3},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+		{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+	}}
+	jarvisJudiceNinkeKernel = DiffusionKernel{Divisor: 48, Weights: []DiffusionWeight{
+		{1, 0, 7}, {2, 0, 5},
+		{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+		{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+	}}
+	stuckiKernel = DiffusionKernel{Divisor: 42, Weights: []DiffusionWeight{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+		{-2, 2, 1}, {-1, 2, 2}, {0, 2, 4}, {1, 2, 2}, {2, 2, 1},
+	}}
+	// Simplified Shadura-style distribution based on the png2pos.c approach
+	shaduraKernel = DiffusionKernel{Divisor: 2, Weights: []DiffusionWeight{
+		{1, 0, 1},
+		{0, 1, 1},
+	}}
+)
 
-	for y := 0; y < height; y++ {
-		result[y] = make([]bool, width)
-		for x := 0; x < width; x++ {
-			// Simple threshold at 128
-			result[y][x] = gray[y][x] < 128
+// validate checks that the kernel only distributes error to unprocessed pixels
+func (k DiffusionKernel) validate() error {
+	if k.Divisor <= 0 {
+		return fmt.Errorf("invalid diffusion kernel divisor %g", k.Divisor)
+	}
+	for _, w := range k.Weights {
+		if w.DY < 0 || (w.DY == 0 && w.DX <= 0) {
+			return fmt.Errorf("diffusion kernel offset (%d,%d) points to an already processed pixel", w.DX, w.DY)
 		}
 	}
-
-	return createMonochromeImage(result, width, height), nil
+	return nil
 }
 
-// applyBayer implements Bayer matrix dithering (4x4)
-func applyBayer(img image.Image) (image.Image, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// 4x4 Bayer matrix
-	bayerMatrix := [][]int{
-		{0, 8, 2, 10},
-		{12, 4, 14, 6},
-		{3, 11, 1, 9},
-		{15, 7, 13, 5},
+// ApplyDiffusionDithering dithers an image with a custom error diffusion kernel.
+// Pixels darker than threshold print black (zero uses 128); serpentine alternates
+// the scan direction every row, mirroring the kernel on right-to-left rows.
+func ApplyDiffusionDithering(img image.Image, kernel DiffusionKernel, threshold uint8, serpentine bool) (image.Image, error) {
+	p := ditherParams{threshold: 128, serpentine: serpentine}
+	if threshold != 0 {
+		p.threshold = int(threshold)
 	}
+	return applyDiffusion(img, kernel, p)
+}
 
-	gray := convertToGrayscale(img)
-	result := make([][]bool, height)
-
-	for y := 0; y < height; y++ {
-		result[y] = make([]bool, width)
-		for x := 0; x < width; x++ {
-			threshold := bayerMatrix[y%4][x%4] * 16
-			result[y][x] = int(gray[y][x]) < threshold
-		}
+// applyDiffusion implements error diffusion dithering with the given kernel
+func applyDiffusion(img image.Image, kernel DiffusionKernel, p ditherParams) (image.Image, error) {
+	if err := kernel.validate(); err != nil {
+		return nil, err
 	}
 
-	return createMonochromeImage(result, width, height), nil
-}
-
-// applyBurkes implements Burkes dithering
-func applyBurkes(img image.Image) (image.Image, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	gray := convertToGrayscale(img)
+	// Convert to grayscale
+	gray := p.grayscale(img)
 
+	// Convert to float64 for error diffusion calculations
 	pixels := make([][]float64, height)
 	for y := 0; y < height; y++ {
 		pixels[y] = make([]float64, width)
@@ -262,14 +410,19 @@ func applyBurkes(img image.Image) (image.Image, error) {
 		result[y] = make([]bool, width)
 	}
 
-	// Apply Burkes dithering
+	scale := p.diffusionScale
+	if scale <= 0 {
+		scale = 1
+	}
+
 	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
+		for i := 0; i < width; i++ {
+			x, dir := p.scanColumn(i, y, width)
 			oldPixel := pixels[y][x]
 			var newPixel float64
 			var isBlack bool
 
-			if oldPixel < 128 {
+			if oldPixel < float64(p.threshold) {
 				newPixel = 0
 				isBlack = true
 			} else {
@@ -278,222 +431,277 @@ func applyBurkes(img image.Image) (image.Image, error) {
 			}
 
 			result[y][x] = isBlack
-			quantError := oldPixel - newPixel
+			quantError := (oldPixel - newPixel) * scale
 
-			// Burkes dithering pattern
-			if x+1 < width {
-				pixels[y][x+1] += quantError * 8.0 / 32.0
-			}
-			if x+2 < width {
-				pixels[y][x+2] += quantError * 4.0 / 32.0
-			}
-			if y+1 < height {
-				if x-2 >= 0 {
-					pixels[y+1][x-2] += quantError * 2.0 / 32.0
-				}
-				if x-1 >= 0 {
-					pixels[y+1][x-1] += quantError * 4.0 / 32.0
-				}
-				pixels[y+1][x] += quantError * 8.0 / 32.0
-				if x+1 < width {
-					pixels[y+1][x+1] += quantError * 4.0 / 32.0
-				}
-				if x+2 < width {
-					pixels[y+1][x+2] += quantError * 2.0 / 32.0
-				}
+			// Distribute error to neighboring pixels
+			for _, w := range kernel.Weights {
+				diffuseError(pixels, x+w.DX*dir, y+w.DY, quantError*w.Weight/kernel.Divisor)
 			}
 		}
+		p.reportProgress(y, height)
+		if err := p.checkCanceled(y); err != nil {
+			return nil, err
+		}
 	}
 
 	return createMonochromeImage(result, width, height), nil
 }
 
-// applySierraLite implements Sierra Lite dithering (Sierra-2-4A)
-func applySierraLite(img image.Image) (image.Image, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	gray := convertToGrayscale(img)
-
-	pixels := make([][]float64, height)
-	for y := 0; y < height; y++ {
-		pixels[y] = make([]float64, width)
-		for x := 0; x < width; x++ {
-			pixels[y][x] = float64(gray[y][x])
-		}
-	}
+// applyFloydSteinberg implements Floyd-Steinberg dithering
+func applyFloydSteinberg(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, floydSteinbergKernel, p)
+}
 
-	result := make([][]bool, height)
-	for y := 0; y < height; y++ {
-		result[y] = make([]bool, width)
-	}
+// applyAtkinson implements Atkinson dithering (error distributed to 6 neighbors)
+func applyAtkinson(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, atkinsonKernel, p)
+}
 
-	// Apply Sierra Lite dithering
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			oldPixel := pixels[y][x]
-			var newPixel float64
-			var isBlack bool
+// applyThreshold implements simple threshold dithering
+func applyThreshold(img image.Image, p ditherParams) (image.Image, error) {
+	return applyOrdered(img, DitheringThreshold, p)
+}
 
-			if oldPixel < 128 {
-				newPixel = 0
-				isBlack = true
-			} else {
-				newPixel = 255
-				isBlack = false
-			}
+// applyBayer implements Bayer matrix dithering (4x4 unless configured otherwise)
+func applyBayer(img image.Image, p ditherParams) (image.Image, error) {
+	return applyOrdered(img, DitheringBayer, p)
+}
 
-			result[y][x] = isBlack
-			quantError := oldPixel - newPixel
+// newBayerMatrix returns the Bayer index matrix of the given size (2, 4 or 8),
+// built from the 2x2 matrix with the recurrence M(2n) = [4M, 4M+2; 4M+3, 4M+1]
+func newBayerMatrix(size int) ([][]int, error) {
+	if size != 2 && size != 4 && size != 8 {
+		return nil, fmt.Errorf("unsupported Bayer matrix size %d (expected 2, 4 or 8)", size)
+	}
 
-			// Sierra Lite dithering pattern
-			if x+1 < width {
-				pixels[y][x+1] += quantError * 2.0 / 4.0
-			}
-			if y+1 < height {
-				if x-1 >= 0 {
-					pixels[y+1][x-1] += quantError * 1.0 / 4.0
-				}
-				pixels[y+1][x] += quantError * 1.0 / 4.0
+	matrix := [][]int{{0, 2}, {3, 1}}
+	for n := 2; n < size; n *= 2 {
+		next := make([][]int, 2*n)
+		for y := range next {
+			next[y] = make([]int, 2*n)
+			for x := range next[y] {
+				quadrant := [2][2]int{{0, 2}, {3, 1}}[y/n][x/n]
+				next[y][x] = 4*matrix[y%n][x%n] + quadrant
 			}
 		}
+		matrix = next
 	}
+	return matrix, nil
+}
 
-	return createMonochromeImage(result, width, height), nil
+// halftoneMatrix is a 6x6 clustered-dot ordered matrix giving the order in which
+// the pixels of a cell turn black: the dot grows in a spiral from the center, so
+// darker tones print as larger dots on a regular grid
+var halftoneMatrix = [6][6]int{
+	{34, 29, 17, 21, 30, 35},
+	{28, 14, 9, 16, 20, 31},
+	{13, 8, 4, 5, 15, 19},
+	{12, 3, 0, 1, 10, 18},
+	{27, 7, 2, 6, 23, 24},
+	{33, 26, 11, 22, 25, 32},
 }
 
-// applyJarvisJudiceNinke implements Jarvis-Judice-Ninke dithering
-func applyJarvisJudiceNinke(img image.Image) (image.Image, error) {
+// applyHalftone implements clustered-dot halftoning with halftoneMatrix
+func applyHalftone(img image.Image, p ditherParams) (image.Image, error) {
+	return applyOrdered(img, DitheringHalftone, p)
+}
+
+// applyOrdered implements the ordered dithering algorithms, which compare every
+// pixel with a threshold depending only on its position (see orderedThreshold)
+func applyOrdered(img image.Image, algo DitheringType, p ditherParams) (image.Image, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	gray := convertToGrayscale(img)
-
-	pixels := make([][]float64, height)
-	for y := 0; y < height; y++ {
-		pixels[y] = make([]float64, width)
-		for x := 0; x < width; x++ {
-			pixels[y][x] = float64(gray[y][x])
-		}
+	threshold, err := orderedThreshold(algo, p)
+	if err != nil {
+		return nil, err
 	}
 
+	gray := p.grayscale(img)
 	result := make([][]bool, height)
-	for y := 0; y < height; y++ {
-		result[y] = make([]bool, width)
-	}
 
-	// Apply Jarvis-Judice-Ninke dithering
 	for y := 0; y < height; y++ {
+		result[y] = make([]bool, width)
 		for x := 0; x < width; x++ {
-			oldPixel := pixels[y][x]
-			var newPixel float64
-			var isBlack bool
-
-			if oldPixel < 128 {
-				newPixel = 0
-				isBlack = true
-			} else {
-				newPixel = 255
-				isBlack = false
-			}
-
-			result[y][x] = isBlack
-			quantError := oldPixel - newPixel
-
-			// Jarvis-Judice-Ninke dithering pattern
-			if x+1 < width {
-				pixels[y][x+1] += quantError * 7.0 / 48.0
-			}
-			if x+2 < width {
-				pixels[y][x+2] += quantError * 5.0 / 48.0
-			}
-			if y+1 < height {
-				if x-2 >= 0 {
-					pixels[y+1][x-2] += quantError * 3.0 / 48.0
-				}
-				if x-1 >= 0 {
-					pixels[y+1][x-1] += quantError * 5.0 / 48.0
-				}
-				pixels[y+1][x] += quantError * 7.0 / 48.0
-				if x+1 < width {
-					pixels[y+1][x+1] += quantError * 5.0 / 48.0
-				}
-				if x+2 < width {
-					pixels[y+1][x+2] += quantError * 3.0 / 48.0
-				}
-			}
-			if y+2 < height {
-				if x-2 >= 0 {
-					pixels[y+2][x-2] += quantError * 1.0 / 48.0
-				}
-				if x-1 >= 0 {
-					pixels[y+2][x-1] += quantError * 3.0 / 48.0
-				}
-				pixels[y+2][x] += quantError * 5.0 / 48.0
-				if x+1 < width {
-					pixels[y+2][x+1] += quantError * 3.0 / 48.0
-				}
-				if x+2 < width {
-					pixels[y+2][x+2] += quantError * 1.0 / 48.0
-				}
-			}
+			result[y][x] = int(gray[y][x]) < threshold(x, y)
 		}
 	}
 
 	return createMonochromeImage(result, width, height), nil
 }
 
-// applyShadura implements a simplified version of the Shadura algorithm
-// Based on the png2pos.c implementation approach
-func applyShadura(img image.Image) (image.Image, error) {
+// orderedThreshold returns the function giving the threshold of the pixel at x, y
+// for an ordered dithering algorithm (Threshold, Bayer or Halftone). Pixels darker
+// than the threshold print black.
+func orderedThreshold(algo DitheringType, p ditherParams) (func(x, y int) int, error) {
+	switch algo {
+	case DitheringThreshold:
+		return func(x, y int) int { return p.threshold }, nil
+	case DitheringBayer:
+		size := p.bayerSize
+		if size == 0 {
+			size = 4
+		}
+		bayerMatrix, err := newBayerMatrix(size)
+		if err != nil {
+			return nil, err
+		}
+		levels := size * size
+
+		// Center each matrix cell in its band of gray levels and shift by the threshold
+		return func(x, y int) int {
+			return bayerMatrix[y%size][x%size]*256/levels + 128/levels + (p.threshold - 128)
+		}, nil
+	case DitheringHalftone:
+		const size = len(halftoneMatrix)
+		const levels = size * size
+
+		// Pixels early in the growth order turn black at the lightest gray levels
+		return func(x, y int) int {
+			order := halftoneMatrix[y%size][x%size]
+			return (levels-1-order)*256/levels + 128/levels + (p.threshold - 128)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%s is not an ordered dithering algorithm", algo)
+	}
+}
+
+// Parameters of the adaptive threshold
+const (
+	// Edge length in pixels of the tiles a threshold is computed for
+	adaptiveTileSize = 32
+
+	// Gray level range below which a tile is considered flat (e.g. blank paper)
+	// and uses the threshold of the whole image, as Otsu's method would split noise
+	adaptiveMinContrast = 32
+)
+
+// applyAdaptive implements adaptive threshold dithering: each tile of
+// adaptiveTileSize pixels gets its own threshold from Otsu's method, and the
+// thresholds are interpolated bilinearly between tile centers to avoid seams.
+// The configured threshold shifts all thresholds by its offset from 128.
+func applyAdaptive(img image.Image, p ditherParams) (image.Image, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	gray := convertToGrayscale(img)
+	gray := p.grayscale(img)
 
-	pixels := make([][]float64, height)
+	var histogram [256]int
 	for y := 0; y < height; y++ {
-		pixels[y] = make([]float64, width)
 		for x := 0; x < width; x++ {
-			pixels[y][x] = float64(gray[y][x])
+			histogram[gray[y][x]]++
+		}
+	}
+	global := otsuThreshold(histogram)
+
+	// Threshold of each tile
+	tilesX := (width + adaptiveTileSize - 1) / adaptiveTileSize
+	tilesY := (height + adaptiveTileSize - 1) / adaptiveTileSize
+	thresholds := make([][]float64, tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		thresholds[ty] = make([]float64, tilesX)
+		for tx := 0; tx < tilesX; tx++ {
+			var tile [256]int
+			lo, hi := uint8(255), uint8(0)
+			for y := ty * adaptiveTileSize; y < min((ty+1)*adaptiveTileSize, height); y++ {
+				for x := tx * adaptiveTileSize; x < min((tx+1)*adaptiveTileSize, width); x++ {
+					v := gray[y][x]
+					tile[v]++
+					lo, hi = min(lo, v), max(hi, v)
+				}
+			}
+			if int(hi)-int(lo) < adaptiveMinContrast {
+				thresholds[ty][tx] = float64(global)
+			} else {
+				thresholds[ty][tx] = float64(otsuThreshold(tile))
+			}
 		}
 	}
 
 	result := make([][]bool, height)
 	for y := 0; y < height; y++ {
 		result[y] = make([]bool, width)
+		y0, y1, wy := adaptiveCell(y, tilesY)
+		for x := 0; x < width; x++ {
+			x0, x1, wx := adaptiveCell(x, tilesX)
+			top := thresholds[y0][x0]*(1-wx) + thresholds[y0][x1]*wx
+			bottom := thresholds[y1][x0]*(1-wx) + thresholds[y1][x1]*wx
+			threshold := top*(1-wy) + bottom*wy + float64(p.threshold-128)
+			result[y][x] = float64(gray[y][x]) < threshold
+		}
 	}
 
-	// Apply Shadura-style dithering (simplified error diffusion)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			oldPixel := pixels[y][x]
-			var newPixel float64
-			var isBlack bool
+	return createMonochromeImage(result, width, height), nil
+}
 
-			if oldPixel < 128 {
-				newPixel = 0
-				isBlack = true
-			} else {
-				newPixel = 255
-				isBlack = false
-			}
+// adaptiveCell returns the indices of the two tiles whose centers surround pixel
+// position pos and the interpolation weight of the second one
+func adaptiveCell(pos, tiles int) (int, int, float64) {
+	f := (float64(pos)+0.5)/adaptiveTileSize - 0.5
+	f = max(0, min(f, float64(tiles-1)))
+	i := int(f)
+	return i, min(i+1, tiles-1), f - float64(i)
+}
 
-			result[y][x] = isBlack
-			quantError := oldPixel - newPixel
+// otsuThreshold returns the threshold that best separates a gray level histogram
+// into dark and light pixels (Otsu's method). Pixels below it are dark.
+func otsuThreshold(histogram [256]int) int {
+	total, sum := 0, 0.0
+	for v, n := range histogram {
+		total += n
+		sum += float64(v * n)
+	}
 
-			// Shadura-style error distribution (simplified pattern)
-			if x+1 < width {
-				pixels[y][x+1] += quantError * 0.5
-			}
-			if y+1 < height {
-				pixels[y+1][x] += quantError * 0.5
-			}
+	best, threshold := -1.0, 128
+	darkCount, darkSum := 0, 0.0
+	for v := 0; v < 255; v++ {
+		darkCount += histogram[v]
+		darkSum += float64(v * histogram[v])
+		lightCount := total - darkCount
+		if darkCount == 0 || lightCount == 0 {
+			continue
+		}
+
+		// Between-class variance of the split after gray level v
+		darkMean := darkSum / float64(darkCount)
+		lightMean := (sum - darkSum) / float64(lightCount)
+		variance := float64(darkCount) * float64(lightCount) * (darkMean - lightMean) * (darkMean - lightMean)
+		if variance > best {
+			best, threshold = variance, v+1
 		}
 	}
+	return threshold
+}
 
-	return createMonochromeImage(result, width, height), nil
+// applyBurkes implements Burkes dithering
+func applyBurkes(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, burkesKernel, p)
+}
+
+// applySierraLite implements Sierra Lite dithering (Sierra-2-4A)
+func applySierraLite(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, sierraLiteKernel, p)
+}
+
+// applyJarvisJudiceNinke implements Jarvis-Judice-Ninke dithering
+func applyJarvisJudiceNinke(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, jarvisJudiceNinkeKernel, p)
+}
+
+// applyStucki implements Stucki dithering (12 neighbors, divisor 42)
+func applyStucki(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, stuckiKernel, p)
+}
+
+// applySierra implements Sierra dithering (Sierra-3, three rows, divisor 32)
+func applySierra(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, sierraKernel, p)
+}
+
+// applyShadura implements a simplified version of the Shadura algorithm
+// Based on the png2pos.c implementation approach
+func applyShadura(img image.Image, p ditherParams) (image.Image, error) {
+	return applyDiffusion(img, shaduraKernel, p)
 }
@@ -0,0 +1,394 @@
+package escposimg
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// countBlack returns the number of black pixels of a dithered image
+func countBlack(img image.Image) int {
+	black := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y == 0 {
+				black++
+			}
+		}
+	}
+	return black
+}
+
+func TestThresholdFlipsMidGray(t *testing.T) {
+	const gray = 100
+	img := solidImage(16, 16, gray)
+
+	tests := []struct {
+		threshold uint8
+		black     int
+	}{
+		{1, 0},
+		{gray - 1, 0},
+		{gray, 0},
+		{gray + 1, 256},
+		{255, 256},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.DitheringAlgo = DitheringThreshold
+		config.Gamma = 1
+		config.Threshold = tt.threshold
+		out, err := ApplyDitheringConfig(img, config)
+		if err != nil {
+			t.Fatalf("threshold %d: ApplyDitheringConfig() error = %v", tt.threshold, err)
+		}
+		if got := countBlack(out); got != tt.black {
+			t.Errorf("threshold %d: %d black pixels, want %d", tt.threshold, got, tt.black)
+		}
+	}
+}
+
+func TestThresholdErrorDiffusion(t *testing.T) {
+	// A single pixel has no neighbors to diffuse to, so it is compared directly
+	const gray = 100
+	img := solidImage(1, 1, gray)
+
+	algos := []DitheringType{
+		DitheringFloydSteinberg, DitheringAtkinson, DitheringBurkes, DitheringSierraLite,
+		DitheringJarvisJudiceNinke, DitheringShadura, DitheringStucki, DitheringSierra,
+	}
+	for _, algo := range algos {
+		for _, threshold := range []uint8{gray, gray + 1} {
+			config := DefaultConfig()
+			config.DitheringAlgo = algo
+			config.Gamma = 1
+			config.Threshold = threshold
+			out, err := ApplyDitheringConfig(img, config)
+			if err != nil {
+				t.Fatalf("%s: ApplyDitheringConfig() error = %v", algo, err)
+			}
+			want := 0
+			if threshold > gray {
+				want = 1
+			}
+			if got := countBlack(out); got != want {
+				t.Errorf("%s, threshold %d: %d black pixels, want %d", algo, threshold, got, want)
+			}
+		}
+	}
+}
+
+func TestInvertComplementsOutput(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 64, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8((x*4 + y*16) % 256)})
+		}
+	}
+
+	tests := []struct {
+		name  string
+		gamma float64
+	}{
+		{"linear", 1},
+		{"gamma", 2.2},
+	}
+	for _, tt := range tests {
+		raster := func(invert bool) []byte {
+			config := DefaultConfig()
+			config.DitheringAlgo = DitheringThreshold
+			config.Gamma = tt.gamma
+			config.NoScale = true
+			config.Invert = invert
+			out, _, err := prepareImage(context.Background(), img, config, 0)
+			if err != nil {
+				t.Fatalf("%s: prepareImage() error = %v", tt.name, err)
+			}
+			data, err := convertToRasterFormat(out)
+			if err != nil {
+				t.Fatalf("%s: convertToRasterFormat() error = %v", tt.name, err)
+			}
+			return data
+		}
+
+		normal, inverted := raster(false), raster(true)
+		if len(normal) != len(inverted) {
+			t.Fatalf("%s: raster sizes %d and %d differ", tt.name, len(normal), len(inverted))
+		}
+		for i := range normal {
+			if inverted[i] != ^normal[i] {
+				t.Errorf("%s: inverted byte %d = %08b, want the complement of %08b", tt.name, i, inverted[i], normal[i])
+				break
+			}
+		}
+	}
+}
+
+// diffusionKernels lists the kernels of the built-in error diffusion algorithms
+var diffusionKernels = []struct {
+	name   string
+	kernel DiffusionKernel
+}{
+	{"floyd-steinberg", floydSteinbergKernel},
+	{"atkinson", atkinsonKernel},
+	{"burkes", burkesKernel},
+	{"sierra-lite", sierraLiteKernel},
+	{"sierra", sierraKernel},
+	{"jarvis-judice-ninke", jarvisJudiceNinkeKernel},
+	{"stucki", stuckiKernel},
+	{"shadura", shaduraKernel},
+}
+
+func TestDiffusionKernelsConserveError(t *testing.T) {
+	for _, tt := range diffusionKernels {
+		if err := tt.kernel.validate(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		sum := 0.0
+		for _, w := range tt.kernel.Weights {
+			sum += w.Weight
+		}
+		// Atkinson deliberately diffuses only 6/8 of the error for more contrast
+		want := tt.kernel.Divisor
+		if tt.name == "atkinson" {
+			want = 6
+		}
+		if sum != want {
+			t.Errorf("%s: weights sum to %g, want %g", tt.name, sum, want)
+		}
+	}
+}
+
+func TestSerpentineMirrorsKernel(t *testing.T) {
+	// The black first row has no quantization error, so the second row, scanned
+	// right to left, must dither like the mirrored row scanned left to right
+	const width = 32
+	img := image.NewGray(image.Rect(0, 0, width, 2))
+	row := image.NewGray(image.Rect(0, 0, width, 1))
+	for x := 0; x < width; x++ {
+		v := uint8(40 + x*5)
+		img.SetGray(x, 1, color.Gray{Y: v})
+		row.SetGray(width-1-x, 0, color.Gray{Y: v})
+	}
+
+	for _, tt := range diffusionKernels {
+		got, err := applyDiffusion(img, tt.kernel, ditherParams{threshold: 128, serpentine: true})
+		if err != nil {
+			t.Fatalf("%s: applyDiffusion() error = %v", tt.name, err)
+		}
+		want, err := applyDiffusion(row, tt.kernel, ditherParams{threshold: 128})
+		if err != nil {
+			t.Fatalf("%s: applyDiffusion() error = %v", tt.name, err)
+		}
+		for x := 0; x < width; x++ {
+			if got.At(x, 1) != want.At(width-1-x, 0) {
+				t.Errorf("%s: serpentine row is not the mirrored left-to-right row at x=%d", tt.name, x)
+				break
+			}
+		}
+	}
+}
+
+func TestBayerMatrix(t *testing.T) {
+	for _, size := range []int{2, 4, 8} {
+		matrix, err := newBayerMatrix(size)
+		if err != nil {
+			t.Fatalf("size %d: newBayerMatrix() error = %v", size, err)
+		}
+		seen := make([]int, size*size)
+		for _, row := range matrix {
+			if len(row) != size {
+				t.Fatalf("size %d: row of length %d", size, len(row))
+			}
+			for _, v := range row {
+				if v < 0 || v >= size*size {
+					t.Fatalf("size %d: value %d out of range", size, v)
+				}
+				seen[v]++
+			}
+		}
+		for v, n := range seen {
+			if n != 1 {
+				t.Errorf("size %d: value %d occurs %d times, want once", size, v, n)
+			}
+		}
+	}
+
+	// The 4x4 matrix keeps the classic layout
+	matrix, _ := newBayerMatrix(4)
+	want := [][]int{{0, 8, 2, 10}, {12, 4, 14, 6}, {3, 11, 1, 9}, {15, 7, 13, 5}}
+	for y := range want {
+		for x := range want[y] {
+			if matrix[y][x] != want[y][x] {
+				t.Fatalf("4x4 matrix = %v, want %v", matrix, want)
+			}
+		}
+	}
+
+	if _, err := newBayerMatrix(16); err == nil {
+		t.Errorf("newBayerMatrix(16) returned no error")
+	}
+}
+
+// blackComponents returns the number of 8-connected groups of black pixels
+func blackComponents(img image.Image) int {
+	bounds := img.Bounds()
+	black := func(x, y int) bool {
+		return image.Pt(x, y).In(bounds) && color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y == 0
+	}
+	seen := map[image.Point]bool{}
+	components := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !black(x, y) || seen[image.Pt(x, y)] {
+				continue
+			}
+			components++
+			stack := []image.Point{{x, y}}
+			seen[image.Pt(x, y)] = true
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						n := image.Pt(p.X+dx, p.Y+dy)
+						if black(n.X, n.Y) && !seen[n] {
+							seen[n] = true
+							stack = append(stack, n)
+						}
+					}
+				}
+			}
+		}
+	}
+	return components
+}
+
+func TestHalftoneClusterGrowth(t *testing.T) {
+	// One cell of the halftone matrix: as the gray level falls, the black dot
+	// must grow from the previous one and stay a single cluster
+	const size = len(halftoneMatrix)
+	var previous image.Image
+	for level := 255; level >= 0; level -= 5 {
+		out, err := ApplyDithering(solidImage(size, size, uint8(level)), DitheringHalftone)
+		if err != nil {
+			t.Fatalf("level %d: ApplyDithering() error = %v", level, err)
+		}
+		if n := blackComponents(out); countBlack(out) > 0 && n != 1 {
+			t.Errorf("level %d: %d separate black clusters, want one", level, n)
+		}
+		if previous != nil {
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					if out.At(x, y) != previous.At(x, y) && color.GrayModel.Convert(out.At(x, y)).(color.Gray).Y != 0 {
+						t.Errorf("level %d: pixel (%d,%d) turned white again", level, x, y)
+					}
+				}
+			}
+		}
+		previous = out
+	}
+
+	// Dispersed dithering scatters the dots of the same cell instead
+	out, err := ApplyDithering(solidImage(size, size, 160), DitheringBayer)
+	if err != nil {
+		t.Fatalf("ApplyDithering() error = %v", err)
+	}
+	if n := blackComponents(out); n < 2 {
+		t.Errorf("Bayer dithering produced %d black clusters, want scattered dots", n)
+	}
+}
+
+func TestGammaGrayscale(t *testing.T) {
+	tests := []struct {
+		name  string
+		pixel color.RGBA
+		gamma float64
+		want  uint8
+	}{
+		// Neutral grays keep their level, as decoding and encoding cancel out
+		{"50% gray without gamma", color.RGBA{128, 128, 128, 255}, 0, 128},
+		{"50% gray with gamma 1", color.RGBA{128, 128, 128, 255}, 1, 128},
+		{"50% gray with gamma 2.2", color.RGBA{128, 128, 128, 255}, 2.2, 128},
+		// Colors are weighted in linear light, which makes them lighter
+		{"50% red without gamma", color.RGBA{128, 0, 0, 255}, 0, 38},
+		{"50% red with gamma 1", color.RGBA{128, 0, 0, 255}, 1, 38},
+		{"50% red with gamma 2.2", color.RGBA{128, 0, 0, 255}, 2.2, 74},
+		{"50% green with gamma 2.2", color.RGBA{0, 128, 0, 255}, 2.2, 100},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		img.SetRGBA(0, 0, tt.pixel)
+		if got := (ditherParams{gamma: tt.gamma}).grayscale(img)[0][0]; got != tt.want {
+			t.Errorf("%s: gray = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyDiffusionDitheringCustomKernel(t *testing.T) {
+	tests := []struct {
+		name   string
+		kernel DiffusionKernel
+		rows   [][]uint8
+		want   []string
+	}{
+		{
+			// 100 prints black and passes +100 to x+2 only: 60 becomes 160 and prints white
+			name:   "two columns right",
+			kernel: DiffusionKernel{Divisor: 1, Weights: []DiffusionWeight{{2, 0, 1}}},
+			rows:   [][]uint8{{100, 60, 60, 60, 60, 60}},
+			want:   []string{"##.##."},
+		},
+		{
+			// The same row with the error passed to x+1 instead
+			name:   "one column right",
+			kernel: DiffusionKernel{Divisor: 1, Weights: []DiffusionWeight{{1, 0, 1}}},
+			rows:   [][]uint8{{100, 60, 60, 60, 60, 60}},
+			want:   []string{"#.###."},
+		},
+		{
+			// Each pixel of the first row lifts the pixel below from 60 to 160
+			name:   "below",
+			kernel: DiffusionKernel{Divisor: 1, Weights: []DiffusionWeight{{0, 1, 1}}},
+			rows:   [][]uint8{{100, 100}, {60, 60}},
+			want:   []string{"##", ".."},
+		},
+		{
+			// A quarter of each error goes below and below right: the second row
+			// receives +25 at x=0 (125, black) and +50 at x=1 (150, white)
+			name:   "divisor",
+			kernel: DiffusionKernel{Divisor: 4, Weights: []DiffusionWeight{{0, 1, 1}, {1, 1, 1}}},
+			rows:   [][]uint8{{100, 100}, {100, 100}},
+			want:   []string{"##", "#."},
+		},
+	}
+	for _, tt := range tests {
+		img := image.NewGray(image.Rect(0, 0, len(tt.rows[0]), len(tt.rows)))
+		for y, row := range tt.rows {
+			copy(img.Pix[y*img.Stride:], row)
+		}
+		out, err := ApplyDiffusionDithering(img, tt.kernel, 128, false)
+		if err != nil {
+			t.Fatalf("%s: ApplyDiffusionDithering() error = %v", tt.name, err)
+		}
+		for y, want := range tt.want {
+			got := make([]byte, len(want))
+			for x := range got {
+				got[x] = '.'
+				if grayAt(out, x, y) == 0 {
+					got[x] = '#'
+				}
+			}
+			if string(got) != want {
+				t.Errorf("%s: row %d = %q, want %q", tt.name, y, got, want)
+			}
+		}
+	}
+
+	invalid := DiffusionKernel{Divisor: 1, Weights: []DiffusionWeight{{-1, 0, 1}}}
+	if _, err := ApplyDiffusionDithering(solidImage(4, 4, 100), invalid, 128, false); err == nil {
+		t.Errorf("ApplyDiffusionDithering() accepted a kernel pointing to a processed pixel")
+	}
+}
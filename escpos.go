@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"log/slog"
+	"math"
+	"slices"
+	"strings"
 )
 
 // ESC/POS command constants
@@ -16,18 +18,53 @@ const (
 	CR  = 0x0D // Carriage return
 )
 
+// Limits of the 16-bit size fields of the image commands
+const (
+	maxRasterWidthBytes = 0xFFFF // GS v 0 xL xH
+	maxRasterHeight     = 0xFFFF // GS v 0 yL yH
+	maxBitImageWidth    = 0xFFFF // ESC * nL nH
+)
+
+// ESC * modes selectable with Config.BitImageMode
+const (
+	// BitImageMode8Dot prints bands of 8 dots with one byte per column (mode 0)
+	BitImageMode8Dot byte = 0
+	// BitImageMode24DotSingle prints bands of 24 dots at single horizontal density (mode 32)
+	BitImageMode24DotSingle byte = 32
+	// BitImageMode24DotDouble prints bands of 24 dots at double horizontal density (mode 33)
+	BitImageMode24DotDouble byte = 33
+)
+
+// bitImageDots returns the number of dots per column of an ESC * mode
+func bitImageDots(mode byte) (int, error) {
+	switch mode {
+	case BitImageMode8Dot:
+		return 8, nil
+	case BitImageMode24DotSingle, BitImageMode24DotDouble:
+		return 24, nil
+	default:
+		return 0, fmt.Errorf("unsupported bit image mode %d (supported: 0, 32, 33)", mode)
+	}
+}
+
 // GenerateESCPOS generates ESC/POS commands from a dithered image
 // Supports both raster mode (GS v 0) and bit image mode (ESC *)
+// Pixels are read relative to the image's bounds, so sub-images are printed correctly.
 func GenerateESCPOS(img image.Image, config *Config) ([]byte, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	slog.Debug("Generating ESC/POS commands",
+	logger().Debug("Generating ESC/POS commands",
 		"width", width,
 		"height", height,
 		"print_mode", config.PrintMode.String())
 
+	// Star printers use their own raster command in every print mode
+	if config.Dialect == DialectStar {
+		return generateStarRaster(img, config)
+	}
+
 	// Dispatch to appropriate mode-specific function
 	switch config.PrintMode {
 	case PrintModeRaster:
@@ -39,6 +76,263 @@ func GenerateESCPOS(img image.Image, config *Config) ([]byte, error) {
 	}
 }
 
+// writePrinterInit writes the printer initialization sequence (ESC @) followed by
+// any mode settings requested in the configuration
+func writePrinterInit(buf *bytes.Buffer, config *Config) {
+	buf.WriteByte(ESC)
+	buf.WriteByte('@')
+	logger().Debug("Added printer initialization command")
+
+	// The mode settings below are Epson commands
+	if config.Dialect == DialectStar {
+		return
+	}
+
+	if config.Smoothing {
+		// GS b n: turn smoothing mode on (n=1)
+		buf.WriteByte(GS)
+		buf.WriteByte('b')
+		buf.WriteByte(1)
+		logger().Debug("Added smoothing command")
+	}
+
+	if config.MotionUnitX != 0 || config.MotionUnitY != 0 {
+		// GS P x y: set the horizontal and vertical motion units
+		buf.WriteByte(GS)
+		buf.WriteByte('P')
+		buf.WriteByte(byte(min(max(config.MotionUnitX, 0), 255)))
+		buf.WriteByte(byte(min(max(config.MotionUnitY, 0), 255)))
+		logger().Debug("Added motion unit command", "x", config.MotionUnitX, "y", config.MotionUnitY)
+	}
+}
+
+// writeSmoothingReset turns smoothing mode off again (GS b 0) if it was enabled
+// during initialization, leaving the printer in its default state
+func writeSmoothingReset(buf *bytes.Buffer, config *Config) {
+	if config.Smoothing && config.Dialect != DialectStar {
+		buf.WriteByte(GS)
+		buf.WriteByte('b')
+		buf.WriteByte(0)
+	}
+}
+
+// writeTextLine writes a line of text followed by a line feed, printed white on
+// black (GS B) when ReverseVideo is enabled
+func writeTextLine(buf *bytes.Buffer, text string, config *Config) {
+	if config.ReverseVideo {
+		buf.Write([]byte{GS, 'B', 1})
+	}
+	buf.WriteString(text)
+	if config.ReverseVideo {
+		buf.Write([]byte{GS, 'B', 0})
+	}
+	buf.WriteByte(LF)
+}
+
+// writeFeedDots feeds the paper by the given number of dots using ESC J,
+// split into several commands when it exceeds the 255 dot limit of one command
+func writeFeedDots(buf *bytes.Buffer, dots int) {
+	for dots > 0 {
+		n := min(dots, 255)
+		buf.WriteByte(ESC)
+		buf.WriteByte('J')
+		buf.WriteByte(byte(n))
+		dots -= n
+	}
+}
+
+// writeFinalFeed feeds the configured blank labels and writes the line feeds that
+// advance the printed content past the tear bar, unless SkipFinalFeed is set.
+// lines is the default used when FeedLinesBeforeCut is nil.
+func writeFinalFeed(buf *bytes.Buffer, lines int, config *Config) {
+	if config.TrailingBlankLabels > 0 && config.LabelHeightMM > 0 {
+		labelDots := int(math.Round(config.LabelHeightMM / 25.4 * float64(config.VerticalDPI())))
+		writeFeedDots(buf, config.TrailingBlankLabels*labelDots)
+		logger().Debug("Added blank labels", "labels", config.TrailingBlankLabels, "label_dots", labelDots)
+	}
+
+	if config.SkipFinalFeed {
+		return
+	}
+	if config.FeedLinesBeforeCut != nil {
+		lines = *config.FeedLinesBeforeCut
+	}
+	for i := 0; i < lines; i++ {
+		buf.WriteByte(LF)
+	}
+}
+
+// writePaperCut writes the paper cut command, or the tear line if requested when
+// the printer has no cutter
+func writePaperCut(buf *bytes.Buffer, config *Config) {
+	if config.NoCutter {
+		if config.TearLine {
+			writeTearLine(buf, config)
+		}
+		logger().Debug("Skipped paper cut, printer has no cutter")
+		return
+	}
+
+	full := config.cutType() == CutFull
+	if config.Dialect == DialectStar {
+		// ESC d n: full (n=0) or partial cut (n=1)
+		if full {
+			buf.Write([]byte{ESC, 'd', 0})
+		} else {
+			buf.Write([]byte{ESC, 'd', 1})
+		}
+		logger().Debug("Added Star paper cut command", "full", full)
+		return
+	}
+
+	// Full (GS V 0) or partial cut command (GS V 1)
+	buf.WriteByte(GS)
+	buf.WriteByte('V')
+	if full {
+		buf.WriteByte(0)
+	} else {
+		buf.WriteByte(1)
+	}
+	logger().Debug("Added paper cut command", "full", full)
+}
+
+// cutType returns the configured cut, mapping CutPaper to CutPartial
+func (c *Config) cutType() CutType {
+	if c.CutType != CutNone {
+		return c.CutType
+	}
+	if c.CutPaper {
+		return CutPartial
+	}
+	return CutNone
+}
+
+// tearLineCharWidth is the width in dots of a character of the printer's default font
+const tearLineCharWidth = 12
+
+// writeTearLine writes a dashed line across the paper marking where to tear it off
+func writeTearLine(buf *bytes.Buffer, config *Config) {
+	chars := max(config.CalculatePixelWidth()/tearLineCharWidth, 1)
+	buf.WriteString(strings.Repeat("-", chars))
+	buf.WriteByte(LF)
+	logger().Debug("Added tear line", "chars", chars)
+}
+
+// writeImage writes the commands printing a monochrome image in the configured print mode,
+// without initialization, feeds or cut. It is used to compose multiple images into one job.
+func writeImage(buf *bytes.Buffer, img image.Image, config *Config) error {
+	if config.Dialect == DialectStar {
+		return writeStarRasterImage(buf, img, config)
+	}
+
+	switch config.PrintMode {
+	case PrintModeRaster:
+		return writeRasterImage(buf, img, config)
+	case PrintModeBitImage:
+		return writeBitImage(buf, img, config)
+	default:
+		return fmt.Errorf("unsupported print mode: %v", config.PrintMode)
+	}
+}
+
+// writeRasterImage converts an image to raster format and writes the GS v 0 command
+func writeRasterImage(buf *bytes.Buffer, img image.Image, config *Config) error {
+	img, err := fitToPaper(img, config)
+	if err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+
+	rasterData, err := convertToRasterFormat(img)
+	if err != nil {
+		return fmt.Errorf("failed to convert image to raster format: %w", err)
+	}
+
+	if err := validateRasterScale(config); err != nil {
+		return err
+	}
+
+	// Justification also applies to the downloaded image printed with GS /
+	writeJustification(buf, config)
+
+	preserved := false
+	if config.PreserveBuffer {
+		if fitsDownloadedImage(bounds.Dx(), bounds.Dy()) {
+			writeDownloadedImage(buf, img, config.RasterScale)
+			preserved = true
+		} else if err := config.warn("Image is too large to keep in the printer's memory and is printed normally",
+			"width", bounds.Dx(), "height", bounds.Dy()); err != nil {
+			return err
+		}
+	}
+
+	// The height field has 16 bits, so taller images are sent as several commands
+	bytesPerLine := (bounds.Dx() + 7) / 8
+	for start := 0; start < bounds.Dy() && !preserved; start += maxRasterHeight {
+		height := min(bounds.Dy()-start, maxRasterHeight)
+		chunk := rasterData[start*bytesPerLine : (start+height)*bytesPerLine]
+		if err := writeRasterImageCommand(buf, bounds.Dx(), height, config.RasterScale, chunk); err != nil {
+			return fmt.Errorf("failed to write raster image command: %w", err)
+		}
+	}
+	resetJustification(buf, config)
+
+	if config.AppendChecksum {
+		writeChecksum(buf, rasterData, config.ChecksumType)
+	}
+	return nil
+}
+
+// writeJustification writes ESC a n, aligning the following lines, if the image
+// is not left-aligned
+func writeJustification(buf *bytes.Buffer, config *Config) {
+	if config.Alignment != AlignLeft {
+		buf.Write([]byte{ESC, 'a', byte(config.Alignment)})
+	}
+}
+
+// resetJustification restores left alignment (ESC a 0) after writeJustification
+func resetJustification(buf *bytes.Buffer, config *Config) {
+	if config.Alignment != AlignLeft {
+		buf.Write([]byte{ESC, 'a', byte(AlignLeft)})
+	}
+}
+
+// writeBitImage converts an image to bit image format and writes the ESC * commands
+func writeBitImage(buf *bytes.Buffer, img image.Image, config *Config) error {
+	img, err := fitToPaper(img, config)
+	if err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+
+	mode := config.BitImageMode
+	bitImageData, err := convertToBitImageFormat(img, mode)
+	if err != nil {
+		return fmt.Errorf("failed to convert image to bit image format: %w", err)
+	}
+
+	// 24-dot bands need a line spacing of exactly 24 dots so they neither
+	// overlap nor leave gaps (ESC 3 n), restored to the default after (ESC 2)
+	dots, _ := bitImageDots(mode)
+	if dots == 24 {
+		buf.Write([]byte{ESC, '3', 24})
+	}
+	writeJustification(buf, config)
+	if err := writeBitImageCommand(buf, mode, bounds.Dx(), bounds.Dy(), bitImageData); err != nil {
+		return fmt.Errorf("failed to write bit image command: %w", err)
+	}
+	resetJustification(buf, config)
+	if dots == 24 {
+		buf.Write([]byte{ESC, '2'})
+	}
+
+	if config.AppendChecksum {
+		writeChecksum(buf, bitImageData, config.ChecksumType)
+	}
+	return nil
+}
+
 // convertToRasterFormat converts a monochrome image to raster format for ESC/POS
 func convertToRasterFormat(img image.Image) ([]byte, error) {
 	bounds := img.Bounds()
@@ -69,16 +363,108 @@ func convertToRasterFormat(img image.Image) ([]byte, error) {
 	return rasterData, nil
 }
 
+// writeRule writes a solid black band of config.RuleHeightPx spanning the printable
+// width, or the width of img when no paper width is configured
+func writeRule(buf *bytes.Buffer, img image.Image, config *Config) error {
+	width := config.CalculatePixelWidth()
+	if width <= 0 {
+		width = img.Bounds().Dx()
+	}
+	return writeSeparator(buf, config.SeparatorArt, width, config)
+}
+
+// writeSeparator writes a separator of the given style, width and config.RuleHeightPx
+func writeSeparator(buf *bytes.Buffer, style SeparatorStyle, width int, config *Config) error {
+	height := config.RuleHeightPx
+	if height <= 0 {
+		height = DefaultRuleHeightPx
+	}
+
+	rule, err := renderSeparator(style, width, height)
+	if err != nil {
+		return err
+	}
+	if err := writeImage(buf, rule, config.ruleConfig()); err != nil {
+		return fmt.Errorf("failed to write rule: %w", err)
+	}
+	logger().Debug("Added rule", "width", width, "height", height, "style", style.String())
+	return nil
+}
+
+// ruleConfig returns a copy of the configuration for printing rules and separators.
+// They are printed directly with GS v 0 or ESC * without a checksum, so that they
+// neither replace the image kept in the printer's memory nor add checksums of their own.
+func (c *Config) ruleConfig() *Config {
+	ruleConfig := *c
+	ruleConfig.PreserveBuffer = false
+	ruleConfig.AppendChecksum = false
+	return &ruleConfig
+}
+
+// fitToPaper scales a dithered image down when it would print wider than the paper,
+// as printers silently truncate the right edge. In strict mode an error is returned.
+func fitToPaper(img image.Image, config *Config) (image.Image, error) {
+	width := img.Bounds().Dx()
+	printWidth := width
+	if config.PrintMode == PrintModeRaster && (config.RasterScale == RasterScaleDoubleWidth || config.RasterScale == RasterScaleQuadruple) {
+		printWidth *= 2
+	}
+
+	// Without a paper width there is nothing to check against
+	paperWidth := max(config.CalculatePixelWidth(), config.PageWidthDots)
+	if paperWidth <= 0 || printWidth <= paperWidth {
+		return img, nil
+	}
+
+	targetWidth := width * paperWidth / printWidth
+	if err := config.warn("Image is wider than the paper and will be scaled down",
+		"image_width", printWidth, "paper_width", paperWidth, "new_width", targetWidth); err != nil {
+		return nil, err
+	}
+
+	// Nearest-neighbor sampling keeps the dithered image black and white
+	return ScaleImageWithFilter(img, targetWidth, 1.0, ScaleFilterNearestNeighbor)
+}
+
+// validateRasterScale checks the configured raster scale against the known values
+// and the scales supported by the printer
+func validateRasterScale(config *Config) error {
+	if config.RasterScale > RasterScaleQuadruple {
+		return fmt.Errorf("invalid raster scale m=%d (expected 0-3)", config.RasterScale)
+	}
+	if len(config.SupportedRasterModes) == 0 || slices.Contains(config.SupportedRasterModes, config.RasterScale) {
+		return nil
+	}
+
+	supported := make([]string, len(config.SupportedRasterModes))
+	for i, mode := range config.SupportedRasterModes {
+		supported[i] = fmt.Sprintf("%s (m=%d)", mode, mode)
+	}
+	return fmt.Errorf("raster scale %s (m=%d) is not supported by the printer (supported: %s)",
+		config.RasterScale, config.RasterScale, strings.Join(supported, ", "))
+}
+
 // writeRasterImageCommand writes the GS v 0 command for raster image printing
-func writeRasterImageCommand(buf *bytes.Buffer, width, height int, rasterData []byte) error {
+func writeRasterImageCommand(buf *bytes.Buffer, width, height int, scale RasterScale, rasterData []byte) error {
 	// Calculate bytes per line
 	bytesPerLine := (width + 7) / 8
 
+	// Both dimensions are encoded as 16-bit little-endian values
+	if bytesPerLine > maxRasterWidthBytes {
+		return fmt.Errorf("raster width of %d bytes exceeds the maximum of %d", bytesPerLine, maxRasterWidthBytes)
+	}
+	if height > maxRasterHeight {
+		return fmt.Errorf("raster height of %d dots exceeds the maximum of %d", height, maxRasterHeight)
+	}
+	if len(rasterData) != bytesPerLine*height {
+		return fmt.Errorf("raster data has %d bytes, expected %d for %dx%d dots", len(rasterData), bytesPerLine*height, width, height)
+	}
+
 	// GS v 0 m xL xH yL yH [data]
-	buf.WriteByte(GS)  // GS
-	buf.WriteByte('v') // v
-	buf.WriteByte('0') // 0
-	buf.WriteByte(0)   // m (normal mode)
+	buf.WriteByte(GS)          // GS
+	buf.WriteByte('v')         // v
+	buf.WriteByte('0')         // 0
+	buf.WriteByte(byte(scale)) // m (scaling mode)
 
 	// Width in bytes (xL + xH * 256)
 	buf.WriteByte(byte(bytesPerLine & 0xFF))        // xL
@@ -91,7 +477,7 @@ func writeRasterImageCommand(buf *bytes.Buffer, width, height int, rasterData []
 	// Write raster data
 	buf.Write(rasterData)
 
-	slog.Debug("Wrote raster image command",
+	logger().Debug("Wrote raster image command",
 		"width_bytes", bytesPerLine,
 		"height", height,
 		"data_size", len(rasterData))
@@ -101,52 +487,62 @@ func writeRasterImageCommand(buf *bytes.Buffer, width, height int, rasterData []
 
 // convertToBitImageFormat converts a monochrome image to bit image format for ESC *.
 //
-// The ESC * command processes images in horizontal bands of 8 pixels height.
-// Each column in a band is represented by a single byte, where each bit
-// corresponds to a vertical pixel (bit 0 = top, bit 7 = bottom).
+// In mode 0 the ESC * command processes images in horizontal bands of 8 pixels
+// height. Each column in a band is represented by a single byte, where each bit
+// corresponds to a vertical pixel (bit 0 = top, bit 7 = bottom). In the 24-dot
+// modes 32 and 33 bands are 24 pixels high and each column takes 3 bytes, with
+// the top pixel in the most significant bit of the first byte.
 //
 // This format is compatible with legacy thermal printers and provides
 // line-by-line processing for better compatibility with older hardware.
 //
 // Parameters:
 //   - img: Source image (should be monochrome/dithered)
+//   - mode: ESC * mode (BitImageMode8Dot, BitImageMode24DotSingle or BitImageMode24DotDouble)
 //
 // Returns:
 //   - []byte: Formatted data ready for ESC * commands
 //   - error: If image processing fails
-func convertToBitImageFormat(img image.Image) ([]byte, error) {
+func convertToBitImageFormat(img image.Image, mode byte) ([]byte, error) {
+	dots, err := bitImageDots(mode)
+	if err != nil {
+		return nil, err
+	}
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	// ESC * mode 0: 8-dot single-density
-	// Each band is 8 pixels high, each column takes 1 byte
-	bands := (height + 7) / 8
-	bytesPerBand := width
+	// Each band is dots pixels high, each column takes dots/8 bytes
+	bytesPerColumn := dots / 8
+	bands := (height + dots - 1) / dots
+	bytesPerBand := width * bytesPerColumn
 	bitImageData := make([]byte, bands*bytesPerBand)
 
 	for band := 0; band < bands; band++ {
 		for x := 0; x < width; x++ {
-			var columnByte byte
-
-			// Process 8 pixels vertically for this column
-			for bit := 0; bit < 8; bit++ {
-				y := band*8 + bit
-				if y < height {
-					// Get pixel color
-					pixel := img.At(x+bounds.Min.X, y+bounds.Min.Y)
-					grayColor := color.GrayModel.Convert(pixel).(color.Gray)
-
-					// Black pixels (Y=0) should print
-					if grayColor.Y < 128 {
+			column := bitImageData[band*bytesPerBand+x*bytesPerColumn:][:bytesPerColumn]
+
+			// Process the pixels of this column vertically
+			for bit := 0; bit < dots; bit++ {
+				y := band*dots + bit
+				if y >= height {
+					break
+				}
+				// Get pixel color
+				pixel := img.At(x+bounds.Min.X, y+bounds.Min.Y)
+				grayColor := color.GrayModel.Convert(pixel).(color.Gray)
+
+				// Black pixels (Y=0) should print
+				if grayColor.Y < 128 {
+					if dots == 8 {
 						// Set bit (bit 0 = top pixel, bit 7 = bottom pixel)
-						columnByte |= 1 << uint(bit)
+						column[0] |= 1 << uint(bit)
+					} else {
+						// Set bit (MSB of the first byte = top pixel)
+						column[bit/8] |= 0x80 >> uint(bit%8)
 					}
 				}
 			}
-
-			// Store the column byte
-			bitImageData[band*bytesPerBand+x] = columnByte
 		}
 	}
 
@@ -156,53 +552,50 @@ func convertToBitImageFormat(img image.Image) ([]byte, error) {
 // writeBitImageCommand writes ESC * commands for bit image printing.
 //
 // Generates a series of ESC * commands to print the image data band by band.
-// Each band represents 8 pixels of height, and the entire image width is
+// Each band represents 8 or 24 pixels of height, and the entire image width is
 // sent with each command. After each band, a line feed advances the paper.
 //
 // Command format for each band: ESC * m nL nH [data]
 // Where:
 //   - ESC * = Start of bit image command
-//   - m = Mode (0 = 8-dot single-density)
+//   - m = Mode (0 = 8-dot single-density, 32/33 = 24-dot single/double-density)
 //   - nL, nH = Width in dots (little-endian 16-bit)
 //   - [data] = Column data for this band
 //
 // Parameters:
 //   - buf: Buffer to write commands to
+//   - mode: ESC * mode
 //   - width: Image width in pixels
 //   - height: Image height in pixels
 //   - bitImageData: Pre-formatted bit image data from convertToBitImageFormat
 //
 // Returns:
 //   - error: If command generation fails
-func writeBitImageCommand(buf *bytes.Buffer, width, height int, bitImageData []byte) error {
-	bands := (height + 7) / 8
-	bytesPerBand := width
+func writeBitImageCommand(buf *bytes.Buffer, mode byte, width, height int, bitImageData []byte) error {
+	if width > maxBitImageWidth {
+		return fmt.Errorf("bit image width of %d dots exceeds the maximum of %d", width, maxBitImageWidth)
+	}
+	dots, err := bitImageDots(mode)
+	if err != nil {
+		return err
+	}
+
+	bands := (height + dots - 1) / dots
+	bytesPerBand := width * dots / 8
 
-	slog.Debug("Writing bit image command",
+	logger().Debug("Writing bit image command",
 		"width", width,
 		"height", height,
 		"bands", bands,
 		"bytes_per_band", bytesPerBand)
 
 	for band := 0; band < bands; band++ {
-		// ESC * m nL nH [data]
-		buf.WriteByte(ESC) // ESC
-		buf.WriteByte('*') // *
-		buf.WriteByte(0)   // m (mode 0: 8-dot single-density)
-
-		// Width in dots (nL + nH * 256)
-		buf.WriteByte(byte(width & 0xFF))        // nL
-		buf.WriteByte(byte((width >> 8) & 0xFF)) // nH
-
-		// Write band data
 		bandStart := band * bytesPerBand
-		bandEnd := bandStart + bytesPerBand
-		buf.Write(bitImageData[bandStart:bandEnd])
-
-		// Line feed after each band
-		buf.WriteByte(LF)
+		if err := appendBitImageBand(buf, mode, width, bitImageData[bandStart:bandStart+bytesPerBand]); err != nil {
+			return err
+		}
 
-		slog.Debug("Wrote bit image band",
+		logger().Debug("Wrote bit image band",
 			"band", band,
 			"data_size", bytesPerBand)
 	}
@@ -210,6 +603,46 @@ func writeBitImageCommand(buf *bytes.Buffer, width, height int, bitImageData []b
 	return nil
 }
 
+// AppendBitImageBand writes one 8-dot band of a bit image: ESC * in mode 0
+// (8-dot single-density) followed by a line feed. bandData holds one byte per
+// column with the top dot in bit 0, as produced for each band of an image.
+//
+// It lets callers stream bands from their own dithering loop while reusing the
+// command encoding. Initialization, feeds and cut are left to the caller.
+func AppendBitImageBand(buf *bytes.Buffer, width int, bandData []byte) error {
+	return appendBitImageBand(buf, BitImageMode8Dot, width, bandData)
+}
+
+// appendBitImageBand writes one band of a bit image in the given ESC * mode
+func appendBitImageBand(buf *bytes.Buffer, mode byte, width int, bandData []byte) error {
+	if width > maxBitImageWidth {
+		return fmt.Errorf("bit image width of %d dots exceeds the maximum of %d", width, maxBitImageWidth)
+	}
+	dots, err := bitImageDots(mode)
+	if err != nil {
+		return err
+	}
+	if expected := width * dots / 8; len(bandData) != expected {
+		return fmt.Errorf("band data has %d bytes, expected %d for %d dots", len(bandData), expected, width)
+	}
+
+	// ESC * m nL nH [data]
+	buf.WriteByte(ESC)  // ESC
+	buf.WriteByte('*')  // *
+	buf.WriteByte(mode) // m
+
+	// Width in dots (nL + nH * 256)
+	buf.WriteByte(byte(width & 0xFF))        // nL
+	buf.WriteByte(byte((width >> 8) & 0xFF)) // nH
+
+	// Write band data
+	buf.Write(bandData)
+
+	// Line feed after each band
+	buf.WriteByte(LF)
+	return nil
+}
+
 // generateRasterMode generates ESC/POS commands using GS v 0 (raster mode).
 //
 // This function implements the modern raster image printing approach using
@@ -235,48 +668,48 @@ func generateRasterMode(img image.Image, config *Config) ([]byte, error) {
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	slog.Debug("Generating raster mode commands", "width", width, "height", height)
+	logger().Debug("Generating raster mode commands", "width", width, "height", height)
 
 	var buf bytes.Buffer
 
 	// Step 1: Initialize printer (ESC @)
-	buf.WriteByte(ESC)
-	buf.WriteByte('@')
-	slog.Debug("Added printer initialization command")
+	writePrinterInit(&buf, config)
 
 	// Step 2: Optional debug text
 	if config.DebugText != "" {
-		buf.WriteString(config.DebugText)
-		buf.WriteByte(LF)
-		slog.Debug("Added debug text", "text", config.DebugText)
+		writeTextLine(&buf, config.DebugText, config)
+		logger().Debug("Added debug text", "text", config.DebugText)
 	}
 
-	// Step 3: Convert image to raster format and generate print commands
-	rasterData, err := convertToRasterFormat(img)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert image to raster format: %w", err)
+	// Step 3-4: Convert image to raster format and generate raster image command (GS v 0)
+	if config.TopRule {
+		if err := writeRule(&buf, img, config); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeRasterImage(&buf, img, config); err != nil {
+		return nil, err
 	}
 
-	// Step 4: Generate raster image command (GS v 0)
-	err = writeRasterImageCommand(&buf, width, height, rasterData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write raster image command: %w", err)
+	if config.BottomRule {
+		if err := writeRule(&buf, img, config); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeImageBarcode(&buf, config); err != nil {
+		return nil, err
 	}
 
+	writeSmoothingReset(&buf, config)
+
 	// Step 5: Feed paper and cut if requested
-	buf.WriteByte(LF)
-	buf.WriteByte(LF)
-	buf.WriteByte(LF)
+	writeFinalFeed(&buf, 3, config)
 
-	if config.CutPaper {
-		// Partial cut command (GS V 1)
-		buf.WriteByte(GS)
-		buf.WriteByte('V')
-		buf.WriteByte(1)
-		slog.Debug("Added paper cut command")
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
 	}
 
-	slog.Debug("Raster mode command generation completed", "total_bytes", buf.Len())
+	logger().Debug("Raster mode command generation completed", "total_bytes", buf.Len())
 	return buf.Bytes(), nil
 }
 
@@ -306,47 +739,48 @@ func generateBitImageMode(img image.Image, config *Config) ([]byte, error) {
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	slog.Debug("Generating bit image mode commands", "width", width, "height", height)
+	logger().Debug("Generating bit image mode commands", "width", width, "height", height)
 
 	var buf bytes.Buffer
 
 	// Step 1: Initialize printer (ESC @)
-	buf.WriteByte(ESC)
-	buf.WriteByte('@')
-	slog.Debug("Added printer initialization command")
+	writePrinterInit(&buf, config)
 
 	// Step 2: Optional debug text
 	if config.DebugText != "" {
-		buf.WriteString(config.DebugText)
-		buf.WriteByte(LF)
-		slog.Debug("Added debug text", "text", config.DebugText)
+		writeTextLine(&buf, config.DebugText, config)
+		logger().Debug("Added debug text", "text", config.DebugText)
 	}
 
-	// Step 3: Convert image to bit image format and generate print commands
-	bitImageData, err := convertToBitImageFormat(img)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert image to bit image format: %w", err)
+	// Step 3-4: Convert image to bit image format and generate bit image commands (ESC *)
+	if config.TopRule {
+		if err := writeRule(&buf, img, config); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeBitImage(&buf, img, config); err != nil {
+		return nil, err
 	}
 
-	// Step 4: Generate bit image commands (ESC *)
-	err = writeBitImageCommand(&buf, width, height, bitImageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write bit image command: %w", err)
+	if config.BottomRule {
+		if err := writeRule(&buf, img, config); err != nil {
+			return nil, err
+		}
 	}
+	if err := writeImageBarcode(&buf, config); err != nil {
+		return nil, err
+	}
+
+	writeSmoothingReset(&buf, config)
 
 	// Step 5: Feed paper and cut if requested
-	buf.WriteByte(LF)
-	buf.WriteByte(LF)
+	writeFinalFeed(&buf, 2, config)
 
-	if config.CutPaper {
-		// Partial cut command (GS V 1)
-		buf.WriteByte(GS)
-		buf.WriteByte('V')
-		buf.WriteByte(1)
-		slog.Debug("Added paper cut command")
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
 	}
 
-	slog.Debug("Bit image mode command generation completed", "total_bytes", buf.Len())
+	logger().Debug("Bit image mode command generation completed", "total_bytes", buf.Len())
 	return buf.Bytes(), nil
 }
 
@@ -0,0 +1,541 @@
+package escposimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// solidImage returns a gray image of the given size filled with one level
+func solidImage(width, height int, level uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = level
+	}
+	return img
+}
+
+// memoryOutput records everything written to it
+type memoryOutput struct {
+	bytes.Buffer
+	writes int
+	closed bool
+}
+
+func (m *memoryOutput) Write(data []byte) error {
+	m.writes++
+	m.Buffer.Write(data)
+	return nil
+}
+
+func (m *memoryOutput) Close() error {
+	m.closed = true
+	return nil
+}
+
+// commandNames returns the names of the parsed commands in data
+func commandNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	commands, err := ParseESCPOS(data)
+	if err != nil {
+		t.Fatalf("ParseESCPOS() error = %v", err)
+	}
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.Name
+	}
+	return names
+}
+
+func TestSmoothing(t *testing.T) {
+	img := solidImage(16, 8, 0)
+	on := []byte{GS, 'b', 1}
+	off := []byte{GS, 'b', 0}
+
+	tests := []struct {
+		name      string
+		smoothing bool
+		mode      PrintMode
+		dialect   Dialect
+		want      bool
+	}{
+		{"raster", true, PrintModeRaster, DialectEpson, true},
+		{"bit image", true, PrintModeBitImage, DialectEpson, true},
+		{"disabled", false, PrintModeRaster, DialectEpson, false},
+		{"star", true, PrintModeRaster, DialectStar, false},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Smoothing = tt.smoothing
+		config.PrintMode = tt.mode
+		config.Dialect = tt.dialect
+		data, err := GenerateESCPOS(img, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateESCPOS() error = %v", tt.name, err)
+		}
+		if got := bytes.Contains(data, on); got != tt.want {
+			t.Errorf("%s: contains GS b 1 = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := bytes.Contains(data, off); got != tt.want {
+			t.Errorf("%s: contains GS b 0 = %v, want %v", tt.name, got, tt.want)
+		}
+		if tt.want && bytes.Index(data, on) > bytes.LastIndex(data, off) {
+			t.Errorf("%s: GS b 0 must follow GS b 1", tt.name)
+		}
+	}
+}
+
+func TestSmoothingTiled(t *testing.T) {
+	config := DefaultConfig()
+	config.Smoothing = true
+	config.DitheringAlgo = DitheringBayer
+
+	var out memoryOutput
+	if err := StreamOrderedRaster(solidImage(16, 8, 0), config, 4, &out); err != nil {
+		t.Fatalf("StreamOrderedRaster() error = %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte{GS, 'b', 0}) {
+		t.Errorf("tiled output does not turn smoothing off")
+	}
+}
+
+func TestWriteRasterImageCommandHeight(t *testing.T) {
+	tests := []struct {
+		height int
+		yL, yH byte
+	}{
+		{255, 0xFF, 0x00},
+		{256, 0x00, 0x01},
+		{257, 0x01, 0x01},
+		{511, 0xFF, 0x01},
+		{512, 0x00, 0x02},
+		{65535, 0xFF, 0xFF},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeRasterImageCommand(&buf, 8, tt.height, RasterScaleNormal, make([]byte, tt.height)); err != nil {
+			t.Fatalf("height %d: writeRasterImageCommand() error = %v", tt.height, err)
+		}
+		header := buf.Bytes()[:8]
+		want := []byte{GS, 'v', '0', 0, 1, 0, tt.yL, tt.yH}
+		if !bytes.Equal(header, want) {
+			t.Errorf("height %d: header = % X, want % X", tt.height, header, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeRasterImageCommand(&buf, 8, 65536, RasterScaleNormal, make([]byte, 65536)); err == nil {
+		t.Errorf("height 65536: writeRasterImageCommand() returned no error")
+	}
+}
+
+func TestWriteRasterImageChunks(t *testing.T) {
+	tests := []struct {
+		height int
+		chunks []int
+	}{
+		{65535, []int{65535}},
+		{65536, []int{65535, 1}},
+		{140000, []int{65535, 65535, 8930}},
+	}
+	for _, tt := range tests {
+		img := solidImage(8, tt.height, 0)
+		var buf bytes.Buffer
+		if err := writeRasterImage(&buf, img, DefaultConfig()); err != nil {
+			t.Fatalf("height %d: writeRasterImage() error = %v", tt.height, err)
+		}
+		commands, err := ParseESCPOS(buf.Bytes())
+		if err != nil {
+			t.Fatalf("height %d: ParseESCPOS() error = %v", tt.height, err)
+		}
+		var chunks []int
+		for _, cmd := range commands {
+			if cmd.Name == "GS v 0" {
+				chunks = append(chunks, int(le16(cmd.Params[3:5])))
+			}
+		}
+		if len(chunks) != len(tt.chunks) {
+			t.Fatalf("height %d: chunks = %v, want %v", tt.height, chunks, tt.chunks)
+		}
+		for i := range chunks {
+			if chunks[i] != tt.chunks[i] {
+				t.Errorf("height %d: chunks = %v, want %v", tt.height, chunks, tt.chunks)
+				break
+			}
+		}
+	}
+}
+
+func TestSkipFinalFeedEndsAtImageData(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		command string
+	}{
+		{"raster", DialectEpson, "GS v 0"},
+		{"star", DialectStar, "ESC GS S"},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Dialect = tt.dialect
+		config.SkipFinalFeed = true
+		config.CutType = CutNone
+
+		var out memoryOutput
+		if err := ProcessImageValue(solidImage(40, 24, 0), config, &out); err != nil {
+			t.Fatalf("%s: ProcessImageValue() error = %v", tt.name, err)
+		}
+		commands, err := ParseESCPOS(out.Bytes())
+		if err != nil {
+			t.Fatalf("%s: ParseESCPOS() error = %v", tt.name, err)
+		}
+		last := commands[len(commands)-1]
+		if last.Name != tt.command || last.Offset+last.Length != out.Len() {
+			t.Errorf("%s: output ends with %s at %d, want it to end with the %s data at %d",
+				tt.name, last.Name, last.Offset+last.Length, tt.command, out.Len())
+		}
+	}
+}
+
+func TestMotionUnitsPrecedeImage(t *testing.T) {
+	img := solidImage(16, 8, 0)
+	tests := []struct {
+		name  string
+		mode  PrintMode
+		image []byte
+	}{
+		{"raster", PrintModeRaster, []byte{GS, 'v', '0'}},
+		{"bit image", PrintModeBitImage, []byte{ESC, '*'}},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PrintMode = tt.mode
+		config.MotionUnitX = 203
+		config.MotionUnitY = 406
+		data, err := GenerateESCPOS(img, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateESCPOS() error = %v", tt.name, err)
+		}
+		// Values above 255 are clamped
+		motion := bytes.Index(data, []byte{GS, 'P', 203, 255})
+		start := bytes.Index(data, tt.image)
+		if motion < 0 || start < 0 || motion > start {
+			t.Errorf("%s: GS P at %d, image at %d, want GS P before the image", tt.name, motion, start)
+		}
+		if names := commandNames(t, data); names[0] != "ESC @" || names[1] != "GS P" {
+			t.Errorf("%s: commands start with %v, want ESC @ and GS P", tt.name, names[:2])
+		}
+	}
+
+	data, err := GenerateESCPOS(img, DefaultConfig())
+	if err != nil {
+		t.Fatalf("GenerateESCPOS() error = %v", err)
+	}
+	if bytes.Contains(data, []byte{GS, 'P'}) {
+		t.Errorf("GS P written without motion units")
+	}
+}
+
+func TestRasterScaleByte(t *testing.T) {
+	img := solidImage(16, 8, 0)
+	for _, scale := range []RasterScale{RasterScaleNormal, RasterScaleDoubleWidth, RasterScaleDoubleHeight, RasterScaleQuadruple} {
+		config := DefaultConfig()
+		config.RasterScale = scale
+		data, err := GenerateESCPOS(img, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateESCPOS() error = %v", scale, err)
+		}
+		start := bytes.Index(data, []byte{GS, 'v', '0'})
+		if start < 0 {
+			t.Fatalf("%s: no GS v 0 command", scale)
+		}
+		if m := data[start+3]; m != byte(scale) {
+			t.Errorf("%s: m = %d, want %d", scale, m, scale)
+		}
+	}
+
+	config := DefaultConfig()
+	config.RasterScale = RasterScaleQuadruple + 1
+	if _, err := GenerateESCPOS(img, config); err == nil {
+		t.Errorf("GenerateESCPOS() with m=4 returned no error")
+	}
+	config.RasterScale = RasterScaleDoubleWidth
+	config.SupportedRasterModes = []RasterScale{RasterScaleNormal}
+	if _, err := GenerateESCPOS(img, config); err == nil {
+		t.Errorf("GenerateESCPOS() with an unsupported scale returned no error")
+	}
+}
+
+func TestCutTypeBytes(t *testing.T) {
+	img := solidImage(16, 8, 0)
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		mode     PrintMode
+		cutType  CutType
+		cutPaper bool
+		want     []byte
+	}{
+		{"none", DialectEpson, PrintModeRaster, CutNone, false, nil},
+		{"partial", DialectEpson, PrintModeRaster, CutPartial, false, []byte{GS, 'V', 1}},
+		{"full", DialectEpson, PrintModeRaster, CutFull, false, []byte{GS, 'V', 0}},
+		{"cut paper", DialectEpson, PrintModeRaster, CutNone, true, []byte{GS, 'V', 1}},
+		{"cut type over cut paper", DialectEpson, PrintModeRaster, CutFull, true, []byte{GS, 'V', 0}},
+		{"bit image full", DialectEpson, PrintModeBitImage, CutFull, false, []byte{GS, 'V', 0}},
+		{"bit image none", DialectEpson, PrintModeBitImage, CutNone, false, nil},
+		{"star none", DialectStar, PrintModeRaster, CutNone, false, nil},
+		{"star partial", DialectStar, PrintModeRaster, CutPartial, false, []byte{ESC, 'd', 1}},
+		{"star full", DialectStar, PrintModeRaster, CutFull, false, []byte{ESC, 'd', 0}},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Dialect = tt.dialect
+		config.PrintMode = tt.mode
+		config.CutType = tt.cutType
+		config.CutPaper = tt.cutPaper
+		data, err := GenerateESCPOS(img, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateESCPOS() error = %v", tt.name, err)
+		}
+		// The cut follows the final line feeds
+		end := bytes.LastIndexByte(data, LF) + 1
+		if got := data[end:]; !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: output ends with % X, want % X", tt.name, got, tt.want)
+		}
+	}
+}
+
+// trailingLineFeeds counts the line feeds directly before the cut command at the
+// end of data
+func trailingLineFeeds(data, cut []byte) int {
+	data = bytes.TrimSuffix(data, cut)
+	n := 0
+	for n < len(data) && data[len(data)-1-n] == LF {
+		n++
+	}
+	return n
+}
+
+func TestFeedLinesBeforeCut(t *testing.T) {
+	img := solidImage(16, 8, 0)
+	lines := func(n int) *int { return &n }
+	cut := []byte{GS, 'V', 1}
+
+	tests := []struct {
+		name  string
+		mode  PrintMode
+		lines *int
+		skip  bool
+		want  int
+	}{
+		{"raster default", PrintModeRaster, nil, false, 3},
+		{"bit image default", PrintModeBitImage, nil, false, 2},
+		{"zero", PrintModeRaster, lines(0), false, 0},
+		{"bit image zero", PrintModeBitImage, lines(0), false, 0},
+		{"five", PrintModeRaster, lines(5), false, 5},
+		{"skip final feed", PrintModeRaster, lines(5), true, 0},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PrintMode = tt.mode
+		config.FeedLinesBeforeCut = tt.lines
+		config.SkipFinalFeed = tt.skip
+		config.CutType = CutPartial
+		data, err := GenerateESCPOS(img, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateESCPOS() error = %v", tt.name, err)
+		}
+		if !bytes.HasSuffix(data, cut) {
+			t.Fatalf("%s: output does not end with the cut", tt.name)
+		}
+		got := trailingLineFeeds(data, cut)
+		if tt.mode == PrintModeBitImage {
+			// The 8-dot bit image band ends with a line feed of its own
+			got--
+		}
+		if got != tt.want {
+			t.Errorf("%s: %d line feeds before the cut, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAlignmentBytes(t *testing.T) {
+	img := solidImage(16, 8, 0)
+	tests := []struct {
+		name  string
+		align Alignment
+		mode  PrintMode
+		want  []byte
+	}{
+		{"left", AlignLeft, PrintModeRaster, nil},
+		{"center", AlignCenter, PrintModeRaster, []byte{ESC, 'a', 1}},
+		{"right", AlignRight, PrintModeRaster, []byte{ESC, 'a', 2}},
+		{"bit image center", AlignCenter, PrintModeBitImage, []byte{ESC, 'a', 1}},
+		{"bit image right", AlignRight, PrintModeBitImage, []byte{ESC, 'a', 2}},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Alignment = tt.align
+		config.PrintMode = tt.mode
+		data, err := GenerateESCPOS(img, config)
+		if err != nil {
+			t.Fatalf("%s: GenerateESCPOS() error = %v", tt.name, err)
+		}
+		if tt.want == nil {
+			if bytes.Contains(data, []byte{ESC, 'a'}) {
+				t.Errorf("%s: ESC a written for left alignment", tt.name)
+			}
+			continue
+		}
+		// ESC a n before the image, ESC a 0 after it
+		imageAt := bytes.Index(data, []byte{GS, 'v', '0'})
+		if tt.mode == PrintModeBitImage {
+			imageAt = bytes.Index(data, []byte{ESC, '*'})
+		}
+		justify := bytes.Index(data, tt.want)
+		reset := bytes.LastIndex(data, []byte{ESC, 'a', 0})
+		if justify < 0 || justify > imageAt || reset < imageAt {
+			t.Errorf("%s: ESC a %d at %d, image at %d, ESC a 0 at %d", tt.name, tt.want[2], justify, imageAt, reset)
+		}
+	}
+}
+
+func TestRulesBypassImageOptions(t *testing.T) {
+	img := solidImage(64, 16, 100)
+
+	config := DefaultConfig()
+	config.TopRule = true
+	config.BottomRule = true
+	config.PreserveBuffer = true
+	data, err := GenerateESCPOS(img, config)
+	if err != nil {
+		t.Fatalf("GenerateESCPOS() error = %v", err)
+	}
+	commands, err := ParseESCPOS(data)
+	if err != nil {
+		t.Fatalf("ParseESCPOS() error = %v", err)
+	}
+	var downloads, rasters int
+	for _, cmd := range commands {
+		switch cmd.Name {
+		case "GS *":
+			downloads++
+			// GS * x y: the image is 64 dots wide, a rule spans the paper
+			if x := int(data[cmd.Offset+2]) * 8; x != 64 {
+				t.Errorf("GS * holds an image %d dots wide, want the 64 dot image", x)
+			}
+		case "GS v 0":
+			rasters++
+		}
+	}
+	if downloads != 1 || rasters != 2 {
+		t.Errorf("got %d GS * and %d GS v 0 commands, want the image downloaded and both rules printed directly", downloads, rasters)
+	}
+
+	// With checksums, the rule adds its GS v 0 command and nothing else
+	config = DefaultConfig()
+	config.AppendChecksum = true
+	without, err := GenerateESCPOS(img, config)
+	if err != nil {
+		t.Fatalf("GenerateESCPOS() error = %v", err)
+	}
+	config.TopRule = true
+	with, err := GenerateESCPOS(img, config)
+	if err != nil {
+		t.Fatalf("GenerateESCPOS() with TopRule error = %v", err)
+	}
+	commands, err = ParseESCPOS(with)
+	if err != nil {
+		t.Fatalf("ParseESCPOS() error = %v", err)
+	}
+	for _, cmd := range commands {
+		if cmd.Name != "GS v 0" {
+			continue
+		}
+		rest := append(append([]byte{}, with[:cmd.Offset]...), with[cmd.Offset+cmd.Length:]...)
+		if !bytes.Equal(rest, without) {
+			t.Errorf("output with a top rule is more than the rule command, want no checksum for the rule")
+		}
+		break
+	}
+}
+
+func TestReverseVideoText(t *testing.T) {
+	img := solidImage(16, 8, 255)
+	tests := []struct {
+		name    string
+		reverse bool
+		want    []byte
+	}{
+		{"normal", false, []byte("Caption\n")},
+		{"reverse", true, append(append([]byte{GS, 'B', 1}, "Caption"...), GS, 'B', 0, LF)},
+	}
+	for _, tt := range tests {
+		for _, mode := range []PrintMode{PrintModeRaster, PrintModeBitImage} {
+			config := DefaultConfig()
+			config.PrintMode = mode
+			config.DebugText = "Caption"
+			config.ReverseVideo = tt.reverse
+
+			data, err := GenerateESCPOS(img, config)
+			if err != nil {
+				t.Fatalf("%s: GenerateESCPOS() error = %v", tt.name, err)
+			}
+			// The text follows ESC @
+			if got := data[2:]; !bytes.HasPrefix(got, tt.want) {
+				t.Errorf("%s, %v: text bytes = % X, want % X", tt.name, mode, got[:min(len(got), len(tt.want))], tt.want)
+			}
+			if !tt.reverse && bytes.Contains(data, []byte{GS, 'B'}) {
+				t.Errorf("%s, %v: GS B written without ReverseVideo", tt.name, mode)
+			}
+		}
+	}
+}
+
+func TestBitImage24DotPacking(t *testing.T) {
+	// Three columns, 24 pixels high: the top pixel, the bottom pixel and rows 8-16
+	img := solidImage(3, 24, 255)
+	img.SetGray(0, 0, color.Gray{})
+	img.SetGray(1, 23, color.Gray{})
+	for y := 8; y <= 16; y++ {
+		img.SetGray(2, y, color.Gray{})
+	}
+	columns := []byte{
+		0x80, 0x00, 0x00,
+		0x00, 0x00, 0x01,
+		0x00, 0xFF, 0x80,
+	}
+
+	for _, mode := range []byte{BitImageMode24DotSingle, BitImageMode24DotDouble} {
+		data, err := convertToBitImageFormat(img, mode)
+		if err != nil {
+			t.Fatalf("mode %d: convertToBitImageFormat() error = %v", mode, err)
+		}
+		if !bytes.Equal(data, columns) {
+			t.Errorf("mode %d: column bytes = % X, want % X", mode, data, columns)
+		}
+
+		// The band is printed with a line spacing of 24 dots, restored afterwards
+		config := DefaultConfig()
+		config.BitImageMode = mode
+		var buf bytes.Buffer
+		if err := writeBitImage(&buf, img, config); err != nil {
+			t.Fatalf("mode %d: writeBitImage() error = %v", mode, err)
+		}
+		want := append(append([]byte{ESC, '3', 24, ESC, '*', mode, 3, 0}, columns...), LF, ESC, '2')
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("mode %d: commands = % X, want % X", mode, buf.Bytes(), want)
+		}
+	}
+
+	// 8-dot bands keep the default line spacing
+	config := DefaultConfig()
+	var buf bytes.Buffer
+	if err := writeBitImage(&buf, img, config); err != nil {
+		t.Fatalf("mode 0: writeBitImage() error = %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte{ESC, '3'}) || bytes.Contains(buf.Bytes(), []byte{ESC, '2'}) {
+		t.Errorf("mode 0: line spacing changed for 8-dot bands")
+	}
+}
@@ -4,69 +4,436 @@
 package escposimg
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log/slog"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
 )
 
 // ProcessImage is the main function that processes an image and sends it to the specified output.
 // It performs the complete pipeline: load → dither → scale → generate ESC/POS → output.
 func ProcessImage(imagePath string, config *Config, output OutputMethod) error {
-	slog.Debug("Starting image processing", "path", imagePath, "config", config)
+	return ProcessImageContext(context.Background(), imagePath, config, output)
+}
+
+// ProcessImageContext is ProcessImage with cancellation: ctx is checked between the
+// pipeline stages and while writing, so a canceled context or an expired deadline
+// aborts processing with an error wrapping ctx.Err(). Writes to a NetworkOutput
+// are interrupted as soon as ctx is done.
+func ProcessImageContext(ctx context.Context, imagePath string, config *Config, output OutputMethod) error {
+	logger().Debug("Opening image", "path", imagePath)
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return fmt.Errorf("failed to load image: failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	return processImageReader(ctx, file, imagePath, config, output)
+}
+
+// ProcessImageReader processes an image read from r, e.g. one held in memory or
+// downloaded over HTTP, and sends it to the specified output (see ProcessImage)
+func ProcessImageReader(r io.Reader, config *Config, output OutputMethod) error {
+	return processImageReader(context.Background(), r, "", config, output)
+}
+
+// processImageReader implements ProcessImageReader, recording source (the image
+// path, if known) in the metadata sidecar of file outputs
+func processImageReader(ctx context.Context, r io.Reader, source string, config *Config, output OutputMethod) error {
+	logger().Debug("Starting image processing", "config", config)
+
+	// The metadata is read from the raw data, so keep it in memory
+	if config.BarcodeMetaKey != "" {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to load image: %w", err)
+		}
+		config = withBarcodeMeta(data, config)
+		r = bytes.NewReader(data)
+	}
 
 	// Step 1: Load the image
-	img, err := LoadImage(imagePath)
+	img, err := LoadImageReader(r)
 	if err != nil {
 		return fmt.Errorf("failed to load image: %w", err)
 	}
-	slog.Debug("Image loaded successfully", "width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	logger().Debug("Image loaded successfully", "width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	if err := checkContext(ctx, config); err != nil {
+		return err
+	}
 
-	// Step 2: Calculate target pixel width based on paper width and DPI
-	targetWidth := config.CalculatePixelWidth()
-	slog.Debug("Target width calculated", "width_pixels", targetWidth, "paper_mm", config.PaperWidthMM, "dpi", config.DPI)
+	return processImageValue(ctx, img, source, config, output)
+}
 
-	// Step 3: Scale the image to fit the paper width
-	scaledImg, err := ScaleImage(img, targetWidth)
+// ProcessImageValue processes an already decoded image, e.g. one drawn with the
+// image package, and sends it to the specified output. It runs the pipeline of
+// ProcessImage after the loading step.
+func ProcessImageValue(img image.Image, config *Config, output OutputMethod) error {
+	return processImageValue(context.Background(), img, "", config, output)
+}
+
+// processImageValue implements ProcessImageValue (see processImageReader for source),
+// aborting when ctx is done
+func processImageValue(ctx context.Context, img image.Image, source string, config *Config, output OutputMethod) error {
+	escposData, err := generateImageCommands(ctx, img, config)
 	if err != nil {
-		return fmt.Errorf("failed to scale image: %w", err)
+		return err
+	}
+	if err := checkContext(ctx, config); err != nil {
+		return err
+	}
+
+	// Send to output
+	if err := writeCommandsContext(ctx, output, escposData, config.InitDelay); err != nil {
+		if ctx.Err() != nil {
+			return checkContext(ctx, config)
+		}
+		return fmt.Errorf("failed to write to output: %w", err)
 	}
-	slog.Debug("Image scaled successfully", "new_width", scaledImg.Bounds().Dx(), "new_height", scaledImg.Bounds().Dy())
+	logger().Debug("Data sent to output successfully")
 
-	// Step 4: Apply dithering algorithm
-	ditheredImg, err := ApplyDithering(scaledImg, config.DitheringAlgo)
+	// Close output
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	if config.FileComment {
+		if err := writeMetaSidecar(output, source, config, escposData); err != nil {
+			return err
+		}
+	}
+
+	logger().Info("Image processing completed successfully")
+	return nil
+}
+
+// GenerateImageCommands runs the processing pipeline for an image and returns the
+// resulting ESC/POS command bytes without sending them to an output.
+func GenerateImageCommands(imagePath string, config *Config) ([]byte, error) {
+	img, err := loadImage(imagePath, config)
 	if err != nil {
-		return fmt.Errorf("failed to apply dithering: %w", err)
+		return nil, err
+	}
+	if config.BarcodeMetaKey != "" {
+		data, err := os.ReadFile(imagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image metadata: %w", err)
+		}
+		config = withBarcodeMeta(data, config)
+	}
+	return generateImageCommands(context.Background(), img, config)
+}
+
+// generateImageCommands runs the processing pipeline after loading an image,
+// aborting when ctx is done or MaxProcessingTime is exceeded
+func generateImageCommands(ctx context.Context, img image.Image, config *Config) ([]byte, error) {
+	ctx, cancel := config.processingContext(ctx)
+	defer cancel()
+
+	// Warn about options that have no effect in the selected print mode
+	if err := config.checkFeatures(); err != nil {
+		return nil, err
+	}
+
+	ditheredImg, content, escposData, err := generateCommands(ctx, img, config, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trade resolution for size until the output fits into the byte budget. Only the
+	// image content is reduced; padding and page placement keep their size.
+	for config.MaxOutputBytes > 0 && len(escposData) > config.MaxOutputBytes {
+		width := content.X
+		newWidth := int(float64(width) * math.Sqrt(float64(config.MaxOutputBytes)/float64(len(escposData))))
+		newWidth = min(newWidth, width-1)
+		if newWidth < minBudgetWidth {
+			return nil, fmt.Errorf("output of %d bytes cannot be reduced to the maximum of %d bytes",
+				len(escposData), config.MaxOutputBytes)
+		}
+		logger().Info("Reducing image width to fit the output byte budget",
+			"size", len(escposData), "max_size", config.MaxOutputBytes, "width", width, "new_width", newWidth)
+
+		ditheredImg, content, escposData, err = generateCommands(ctx, img, config, newWidth)
+		if err != nil {
+			return nil, err
+		}
+		if content.X >= width {
+			return nil, fmt.Errorf("output of %d bytes cannot be reduced to the maximum of %d bytes: image width stays at %d px",
+				len(escposData), config.MaxOutputBytes, content.X)
+		}
 	}
-	slog.Debug("Dithering applied successfully", "algorithm", config.DitheringAlgo.String())
 
-	// Step 5: Save debug image if requested
+	// Save debug image if requested
 	if config.DebugOutput {
 		if err := SaveDebugImage(ditheredImg, config.DebugImagePath); err != nil {
-			slog.Warn("Failed to save debug image", "error", err)
+			logger().Warn("Failed to save debug image", "error", err)
 		} else {
-			slog.Debug("Debug image saved", "path", config.DebugImagePath)
+			logger().Debug("Debug image saved", "path", config.DebugImagePath)
 		}
 	}
 
-	// Step 6: Generate ESC/POS commands
-	escposData, err := GenerateESCPOS(ditheredImg, config)
+	logger().Debug("ESC/POS commands generated", "data_size", len(escposData))
+	return escposData, nil
+}
+
+// minBudgetWidth is the narrowest image width MaxOutputBytes may reduce an image to
+const minBudgetWidth = 8
+
+// generateCommands prepares a loaded image and generates its ESC/POS commands,
+// limiting the image width to widthLimit pixels when it is positive. It also returns
+// the size the image content was scaled to, before padding and page placement.
+func generateCommands(ctx context.Context, img image.Image, config *Config, widthLimit int) (image.Image, image.Point, []byte, error) {
+	// The black plane of two-color output may use its own algorithm
+	prepareConfig := config
+	if config.RedMaskPath != "" && config.BlackAlgo != nil {
+		prepareConfig = config.withDitheringAlgo(*config.BlackAlgo)
+	}
+	ditheredImg, content, err := prepareImage(ctx, img, prepareConfig, widthLimit)
 	if err != nil {
-		return fmt.Errorf("failed to generate ESC/POS commands: %w", err)
+		return nil, image.Point{}, nil, err
 	}
-	slog.Debug("ESC/POS commands generated", "data_size", len(escposData))
 
-	// Step 7: Send to output
-	if err := output.Write(escposData); err != nil {
-		return fmt.Errorf("failed to write to output: %w", err)
+	// Generate ESC/POS commands, using both color planes when a red mask is given
+	// and one receipt per tile when tiling is enabled
+	var escposData []byte
+	if config.RedMaskPath != "" {
+		var mask image.Image
+		mask, err = PrepareRedMask(config, content.X, content.Y)
+		if err != nil {
+			return nil, image.Point{}, nil, err
+		}
+		escposData, err = GenerateTwoColorESCPOS(ditheredImg, mask, config)
+	} else if config.TileHeightPx > 0 {
+		escposData, err = GenerateTiledESCPOS(ditheredImg, config)
+	} else {
+		escposData, err = GenerateESCPOS(ditheredImg, config)
 	}
-	slog.Debug("Data sent to output successfully")
+	if err != nil {
+		return nil, image.Point{}, nil, fmt.Errorf("failed to generate ESC/POS commands: %w", err)
+	}
+	return ditheredImg, content, escposData, nil
+}
 
-	// Step 8: Close output
-	if err := output.Close(); err != nil {
-		return fmt.Errorf("failed to close output: %w", err)
+// GenerateImageCommandsHex returns the commands of GenerateImageCommands as a
+// lowercase hex string, for pasting into debugging tools
+func GenerateImageCommandsHex(imagePath string, config *Config) (string, error) {
+	data, err := GenerateImageCommands(imagePath, config)
+	if err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(data), nil
+}
 
-	slog.Info("Image processing completed successfully")
-	return nil
+// GenerateImageCommandsBase64 returns the commands of GenerateImageCommands as a
+// standard base64 string, for transports that only carry text
+func GenerateImageCommandsBase64(imagePath string, config *Config) (string, error) {
+	data, err := GenerateImageCommands(imagePath, config)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// PrepareImage loads an image, scales it to the paper width and applies dithering,
+// returning the monochrome image that would be sent to the printer.
+func PrepareImage(imagePath string, config *Config) (image.Image, error) {
+	img, err := loadImage(imagePath, config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := config.processingContext(context.Background())
+	defer cancel()
+	ditheredImg, _, err := prepareImage(ctx, img, config, 0)
+	return ditheredImg, err
+}
+
+// loadImage loads the image to process (step 1 of the pipeline)
+func loadImage(imagePath string, config *Config) (image.Image, error) {
+	logger().Debug("Starting image processing", "path", imagePath, "config", config)
+
+	// Step 1: Load the image
+	img, err := LoadImage(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
+	}
+	logger().Debug("Image loaded successfully", "width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	return img, nil
+}
+
+// prepareImage lays out and dithers a loaded image, limiting its width to
+// widthLimit pixels when it is positive. It also returns the size the image
+// content was scaled to, before padding and page placement.
+func prepareImage(ctx context.Context, img image.Image, config *Config, widthLimit int) (image.Image, image.Point, error) {
+	// Steps 2-6: Scale, pad, mirror and place the image for the paper
+	scaledImg, content, err := layoutImage(img, config, widthLimit)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	if err := checkContext(ctx, config); err != nil {
+		return nil, image.Point{}, err
+	}
+
+	// Step 7: Apply dithering algorithm
+	ditheredImg, err := applyDitheringContext(ctx, scaledImg, config)
+	if err != nil {
+		if ctxErr := checkContext(ctx, config); ctxErr != nil {
+			return nil, image.Point{}, ctxErr
+		}
+		return nil, image.Point{}, fmt.Errorf("failed to apply dithering: %w", err)
+	}
+	logger().Debug("Dithering applied successfully", "algorithm", config.DitheringAlgo.String())
+
+	return ditheredImg, content, nil
+}
+
+// processingContext derives the context the pipeline runs under from ctx,
+// limited to MaxProcessingTime when it is set
+func (c *Config) processingContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.MaxProcessingTime > 0 {
+		return context.WithTimeout(ctx, c.MaxProcessingTime)
+	}
+	return context.WithCancel(ctx)
+}
+
+// checkContext returns an error if processing was canceled or ran out of time
+func checkContext(ctx context.Context, config *Config) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) && config.MaxProcessingTime > 0 {
+		return fmt.Errorf("processing exceeded the maximum time of %s: %w", config.MaxProcessingTime, err)
+	}
+	return fmt.Errorf("processing aborted: %w", err)
+}
+
+// layoutImage rotates, scales, pads and mirrors a loaded image for the configured paper,
+// returning the image that is passed to the dithering step and the size its content
+// was scaled to. A positive widthLimit caps the width the image is scaled to.
+func layoutImage(img image.Image, config *Config, widthLimit int) (image.Image, image.Point, error) {
+	// Step 2: Calculate target pixel width based on paper width, DPI and padding
+	padding := config.PaddingPx
+	if padding.Top < 0 || padding.Right < 0 || padding.Bottom < 0 || padding.Left < 0 {
+		return nil, image.Point{}, fmt.Errorf("padding must not be negative (top %d, right %d, bottom %d, left %d px)",
+			padding.Top, padding.Right, padding.Bottom, padding.Left)
+	}
+	targetWidth := config.CalculatePixelWidth() - padding.Left - padding.Right
+	if targetWidth <= 0 {
+		return nil, image.Point{}, fmt.Errorf("horizontal padding (%d+%d px) leaves no room for the image on %d px paper",
+			padding.Left, padding.Right, config.CalculatePixelWidth())
+	}
+	if widthLimit > 0 {
+		targetWidth = min(targetWidth, widthLimit)
+	}
+	logger().Debug("Target width calculated", "width_pixels", targetWidth, "paper_mm", config.PaperWidthMM, "width_dots", config.WidthDots, "dpi_x", config.HorizontalDPI(), "dpi_y", config.VerticalDPI())
+
+	return transformImage(img, config, func(img image.Image) (image.Image, error) {
+		// Step 3: Scale the image to fit the paper width
+		if config.NoScale {
+			if img.Bounds().Dx() <= targetWidth {
+				return img, nil
+			}
+			if err := config.warn("Image is wider than the paper and will be scaled down despite NoScale",
+				"image_width", img.Bounds().Dx(), "target_width", targetWidth); err != nil {
+				return nil, err
+			}
+		} else if img.Bounds().Dx() < targetWidth {
+			if err := config.warn("Image is narrower than the paper and will be upscaled",
+				"image_width", img.Bounds().Dx(), "target_width", targetWidth); err != nil {
+				return nil, err
+			}
+		}
+		scaledImg, err := ScaleImageConfig(img, targetWidth, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scale image: %w", err)
+		}
+		return scaledImg, nil
+	})
+}
+
+// transformImage inverts and rotates an image, scales it with scale, then pads,
+// mirrors and places it on the page as configured. The black plane and the red mask
+// of two-color output both go through it, so that the planes stay in register.
+// It returns the transformed image and the size of the scaled content.
+func transformImage(img image.Image, config *Config, scale func(image.Image) (image.Image, error)) (image.Image, image.Point, error) {
+	// Invert the image first, so that padding and rotated corners stay white
+	if config.Invert {
+		img = invertImage(img)
+		logger().Debug("Image inverted")
+	}
+
+	// Rotate the image before scaling, so it is scaled to the paper as rotated
+	if config.Rotation != 0 {
+		rotated, err := RotateImage(img, config.Rotation)
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+		img = rotated
+		logger().Debug("Image rotated", "rotation", config.Rotation,
+			"width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	}
+	if config.RotateDegrees != 0 {
+		img = RotateImageArbitrary(img, config.RotateDegrees, color.White)
+		logger().Debug("Image rotated", "degrees", config.RotateDegrees,
+			"width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	}
+
+	scaledImg, err := scale(img)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	content := scaledImg.Bounds().Size()
+	logger().Debug("Image scaled successfully", "new_width", content.X, "new_height", content.Y)
+
+	// Step 4: Surround the image with whitespace if requested
+	if padding := config.PaddingPx; padding != (Padding{}) {
+		scaledImg = AddPadding(scaledImg, padding)
+		logger().Debug("Padding added", "top", padding.Top, "right", padding.Right, "bottom", padding.Bottom, "left", padding.Left)
+	}
+
+	// Step 5: Mirror the image as requested and for iron-on transfer media
+	horizontal := config.FlipHorizontal != config.TransferMirror
+	if horizontal || config.FlipVertical {
+		scaledImg = FlipImage(scaledImg, horizontal, config.FlipVertical)
+		logger().Debug("Image flipped", "horizontal", horizontal, "vertical", config.FlipVertical)
+	}
+
+	// Step 6: Place the image on a wider page
+	if config.PageWidthDots > scaledImg.Bounds().Dx() {
+		scaledImg, err = placeOnPage(scaledImg, config)
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+	}
+
+	return scaledImg, content, nil
+}
+
+// placeOnPage moves an image to the right so that it is centered on a page of
+// PageWidthDots, or starts at OffsetXPx
+func placeOnPage(img image.Image, config *Config) (image.Image, error) {
+	width := img.Bounds().Dx()
+	offset := (config.PageWidthDots - width) / 2
+	if config.OffsetXPx > 0 {
+		offset = config.OffsetXPx
+		if offset+width > config.PageWidthDots {
+			offset = config.PageWidthDots - width
+			if err := config.warn("Image does not fit on the page at the requested offset and is moved left",
+				"offset", config.OffsetXPx, "image_width", width, "page_width", config.PageWidthDots, "new_offset", offset); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	logger().Debug("Image placed on page", "offset", offset, "image_width", width, "page_width", config.PageWidthDots)
+	return AddPadding(img, Padding{Left: offset}), nil
 }
 
 // Version returns the current version of the escposimg library
@@ -0,0 +1,314 @@
+package escposimg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePNG encodes img to a PNG file in a temporary directory and returns its path
+func writePNG(t *testing.T, img image.Image) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "image.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// patternImage returns an image with a distinct, asymmetric pattern so that any
+// shift or mirroring changes the output
+func patternImage(rect image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			v := uint8((x*7 + y*13 + x*y) % 256)
+			img.Set(x, y, color.RGBA{R: v, G: 255 - v, B: uint8(x * 3), A: 255})
+		}
+	}
+	return img
+}
+
+func TestPipelineSubImage(t *testing.T) {
+	// The sub-image and a copy of it at the origin must print identically
+	parent := patternImage(image.Rect(0, 0, 300, 200))
+	rect := image.Rect(37, 21, 237, 141)
+	sub := parent.SubImage(rect)
+	origin := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(origin, origin.Bounds(), sub, rect.Min, draw.Src)
+
+	tests := []struct {
+		name   string
+		config func(*Config)
+	}{
+		{"raster", func(c *Config) {}},
+		{"bit image", func(c *Config) { c.PrintMode = PrintModeBitImage }},
+		{"bayer", func(c *Config) { c.DitheringAlgo = DitheringBayer }},
+		{"no scaling", func(c *Config) { c.NoScale = true }},
+		{"rotated", func(c *Config) { c.Rotation = 90 }},
+		{"straightened", func(c *Config) { c.RotateDegrees = 3 }},
+		{"flipped", func(c *Config) { c.FlipHorizontal = true; c.FlipVertical = true }},
+		{"padded", func(c *Config) { c.PaddingPx = Padding{Left: 13, Right: 5} }},
+		{"inverted", func(c *Config) { c.Invert = true }},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+
+		var got, want memoryOutput
+		if err := ProcessImageValue(sub, config, &got); err != nil {
+			t.Fatalf("%s: ProcessImageValue(sub-image) error = %v", tt.name, err)
+		}
+		if err := ProcessImageValue(origin, config, &want); err != nil {
+			t.Fatalf("%s: ProcessImageValue(copy) error = %v", tt.name, err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%s: sub-image output differs from the output of a copy at the origin", tt.name)
+		}
+	}
+}
+
+func TestGenerateESCPOSSubImage(t *testing.T) {
+	// A dithered image cropped to a nonzero origin
+	parent := solidImage(64, 32, 255)
+	for x := 16; x < 24; x++ {
+		parent.SetGray(x, 8, color.Gray{})
+	}
+	sub := parent.SubImage(image.Rect(16, 8, 32, 16))
+
+	data, err := GenerateESCPOS(sub, DefaultConfig())
+	if err != nil {
+		t.Fatalf("GenerateESCPOS() error = %v", err)
+	}
+	commands, err := ParseESCPOS(data)
+	if err != nil {
+		t.Fatalf("ParseESCPOS() error = %v", err)
+	}
+	for _, cmd := range commands {
+		if cmd.Name != "GS v 0" {
+			continue
+		}
+		want := []byte{0xFF, 0x00}
+		if !bytes.Equal(cmd.Data[:2], want) {
+			t.Errorf("first raster row = % X, want % X", cmd.Data[:2], want)
+		}
+		return
+	}
+	t.Errorf("no GS v 0 command in output")
+}
+
+func TestMaxProcessingTime(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxProcessingTime = time.Millisecond
+
+	var out memoryOutput
+	start := time.Now()
+	err := ProcessImageValue(patternImage(image.Rect(0, 0, 3000, 4000)), config, &out)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ProcessImageValue() error = %v, want context.DeadlineExceeded", err)
+	}
+	if out.Len() != 0 || out.closed {
+		t.Errorf("output received %d bytes (closed: %v) after the timeout", out.Len(), out.closed)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("timeout fired after %s", elapsed)
+	}
+
+	// Without a limit the same image is processed
+	config.MaxProcessingTime = 0
+	if err := ProcessImageValue(patternImage(image.Rect(0, 0, 300, 400)), config, &out); err != nil {
+		t.Errorf("ProcessImageValue() without MaxProcessingTime error = %v", err)
+	}
+}
+
+func TestProcessImageContextCanceled(t *testing.T) {
+	path := writePNG(t, patternImage(image.Rect(0, 0, 200, 600)))
+
+	tests := []struct {
+		name   string
+		cancel func(cancel context.CancelFunc, config *Config)
+	}{
+		{"before processing", func(cancel context.CancelFunc, config *Config) { cancel() }},
+		{"while dithering", func(cancel context.CancelFunc, config *Config) {
+			config.DitherProgress = func(rowsDone, totalRows int) { cancel() }
+		}},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		config := DefaultConfig()
+		tt.cancel(cancel, config)
+
+		var out memoryOutput
+		err := ProcessImageContext(ctx, path, config, &out)
+		cancel()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: ProcessImageContext() error = %v, want context.Canceled", tt.name, err)
+		}
+		if out.writes != 0 || out.closed {
+			t.Errorf("%s: output received %d writes (closed: %v) after cancellation", tt.name, out.writes, out.closed)
+		}
+	}
+}
+
+func TestLayoutImagePadding(t *testing.T) {
+	tests := []struct {
+		name    string
+		padding Padding
+		wantErr bool
+	}{
+		{"none", Padding{}, false},
+		{"all sides", Padding{Top: 4, Right: 8, Bottom: 2, Left: 16}, false},
+		{"negative top", Padding{Top: -1}, true},
+		{"negative left", Padding{Left: -10}, true},
+		{"negative right", Padding{Right: -10}, true},
+		{"negative bottom", Padding{Bottom: -3}, true},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PaddingPx = tt.padding
+		img, _, err := layoutImage(solidImage(64, 32, 100), config, 0)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: layoutImage() succeeded, want an error for negative padding", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: layoutImage() error = %v", tt.name, err)
+			continue
+		}
+		if got := img.Bounds().Dx(); got != config.CalculatePixelWidth() {
+			t.Errorf("%s: width = %d, want the paper width %d", tt.name, got, config.CalculatePixelWidth())
+		}
+	}
+}
+
+func TestMaxOutputBytesPageWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int
+		wantErr  bool
+	}{
+		{"fits after reduction", 5000, false},
+		{"page padding alone exceeds the budget", 100, true},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PaperWidthMM = 48
+		config.PageWidthDots = 576
+		config.MaxOutputBytes = tt.maxBytes
+
+		done := make(chan struct{})
+		var data []byte
+		var err error
+		go func() {
+			defer close(done)
+			data, err = generateImageCommands(context.Background(), patternImage(image.Rect(0, 0, 400, 400)), config)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: generateImageCommands() did not return", tt.name)
+		}
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: generateImageCommands() returned %d bytes, want an error", tt.name, len(data))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: generateImageCommands() error = %v", tt.name, err)
+		}
+		if len(data) > tt.maxBytes {
+			t.Errorf("%s: output is %d bytes, want at most %d", tt.name, len(data), tt.maxBytes)
+		}
+		commands, err := ParseESCPOS(data)
+		if err != nil {
+			t.Fatalf("%s: ParseESCPOS() error = %v", tt.name, err)
+		}
+		for _, cmd := range commands {
+			if cmd.Name == "GS v 0" {
+				// GS v 0 m xL xH yL yH: the width in bytes. The reduced image stays
+				// centered on the page, so it ends right of the page center.
+				if width := (int(data[cmd.Offset+4]) | int(data[cmd.Offset+5])<<8) * 8; width < 576/2 || width > 576 {
+					t.Errorf("%s: raster is %d dots wide, want the image centered on the 576 dot page", tt.name, width)
+				}
+			}
+		}
+	}
+}
+
+func TestProcessImageReader(t *testing.T) {
+	src := patternImage(image.Rect(0, 0, 120, 80))
+	var data bytes.Buffer
+	if err := png.Encode(&data, src); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	config := DefaultConfig()
+	want, err := GenerateImageCommands(writePNG(t, src), config)
+	if err != nil {
+		t.Fatalf("GenerateImageCommands() error = %v", err)
+	}
+
+	var out memoryOutput
+	if err := ProcessImageReader(bytes.NewReader(data.Bytes()), config, &out); err != nil {
+		t.Fatalf("ProcessImageReader() error = %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) || !out.closed {
+		t.Errorf("output got %d bytes (closed: %v), want the %d bytes generated from the file", out.Len(), out.closed, len(want))
+	}
+
+	if err := ProcessImageReader(bytes.NewReader([]byte("not an image")), config, &out); err == nil {
+		t.Errorf("ProcessImageReader() accepted data that is not an image")
+	}
+}
+
+func TestLayoutImagePageWidthLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		offset     int
+		widthLimit int
+		wantLeft   int
+	}{
+		{"centered", 0, 0, (576 - 384) / 2},
+		{"centered with width limit", 0, 200, (576 - 200) / 2},
+		{"offset", 40, 0, 40},
+		{"offset with width limit", 40, 200, 40},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PaperWidthMM = 48
+		config.PageWidthDots = 576
+		config.OffsetXPx = tt.offset
+		img, content, err := layoutImage(solidImage(400, 100, 0), config, tt.widthLimit)
+		if err != nil {
+			t.Fatalf("%s: layoutImage() error = %v", tt.name, err)
+		}
+
+		wantContent := config.CalculatePixelWidth()
+		if tt.widthLimit > 0 {
+			wantContent = tt.widthLimit
+		}
+		if content.X != wantContent {
+			t.Errorf("%s: content width = %d, want %d", tt.name, content.X, wantContent)
+		}
+		// Only the content is limited, it is still placed on the page as configured
+		if got := blackColumns(img, 0); len(got) != content.X || got[0] != tt.wantLeft {
+			t.Errorf("%s: %d black dots starting at %v, want %d starting at %d", tt.name, len(got), got[:min(len(got), 1)], content.X, tt.wantLeft)
+		}
+	}
+}
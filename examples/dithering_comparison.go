@@ -29,6 +29,10 @@ func main() {
 		escposimg.DitheringBurkes,
 		escposimg.DitheringSierraLite,
 		escposimg.DitheringJarvisJudiceNinke,
+		escposimg.DitheringStucki,
+		escposimg.DitheringSierra,
+		escposimg.DitheringHalftone,
+		escposimg.DitheringAdaptive,
 		escposimg.DitheringShadura,
 	}
 
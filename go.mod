@@ -2,4 +2,9 @@ module github.com/72nd/escposimg
 
 go 1.23.3
 
-require github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+require (
+	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+	golang.org/x/image v0.28.0
+)
+
+require golang.org/x/text v0.26.0 // indirect
@@ -0,0 +1,136 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// gridSquares is the number of grid squares printed vertically by GenerateGrid
+const gridSquares = 10
+
+// GenerateGrid generates a calibration grid spanning the full printable width of
+// the configured paper. Grid lines are one dot wide and repeat every squarePx dots
+// (10 if squarePx is not positive); a closing line marks the last printable column.
+//
+// The grid is preceded by its dimensions in dots and in millimeters as computed from
+// the configured DPI, so that measuring the print with a ruler verifies the paper
+// width and DPI settings end to end. The grid is sent unscaled as a raster image.
+func GenerateGrid(config *Config, squarePx int) []byte {
+	if squarePx <= 0 {
+		squarePx = 10
+	}
+	width := config.CalculatePixelWidth()
+	height := min(gridSquares*squarePx+1, maxRasterHeight)
+
+	grid := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.Gray{Y: 255}
+			if x%squarePx == 0 || y%squarePx == 0 || x == width-1 {
+				c = color.Gray{Y: 0}
+			}
+			grid.SetGray(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	// Label the grid with the dimensions it is expected to print at
+	mmX := 25.4 / float64(config.HorizontalDPI())
+	mmY := 25.4 / float64(config.VerticalDPI())
+	writeTextLine(&buf, fmt.Sprintf("Grid: %d dot squares (%.2f x %.2f mm)",
+		squarePx, float64(squarePx)*mmX, float64(squarePx)*mmY), config)
+	writeTextLine(&buf, fmt.Sprintf("Width: %d dots (%.1f mm at %d DPI)",
+		width, float64(width)*mmX, config.HorizontalDPI()), config)
+	writeTextLine(&buf, fmt.Sprintf("Height: %d dots (%.1f mm at %d DPI)",
+		height, float64(height)*mmY, config.VerticalDPI()), config)
+	buf.WriteByte(LF)
+
+	rasterData, err := convertToRasterFormat(grid)
+	if err == nil {
+		err = writeRasterImageCommand(&buf, width, height, RasterScaleNormal, rasterData)
+	}
+	if err != nil {
+		logger().Error("Failed to write calibration grid", "error", err)
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	// Feed and cut if requested
+	writeFinalFeed(&buf, 2, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	return buf.Bytes()
+}
+
+// gradientHeight is the height in dots of the band printed by GenerateGradient
+const gradientHeight = 96
+
+// GenerateGradient generates a calibration strip with a horizontal gray gradient
+// spanning the full printable width, from white (input level 255) on the left to
+// black (input level 0) on the right. The gradient is dithered with the configured
+// algorithm and threshold and printed in the configured print mode.
+//
+// Ticks below the gradient mark every tenth of the input range, so the level at
+// which the printer starts laying down ink can be read off the print when tuning
+// Threshold or the printer density.
+func GenerateGradient(config *Config) []byte {
+	width := config.CalculatePixelWidth()
+
+	gradient := image.NewGray(image.Rect(0, 0, width, gradientHeight))
+	for x := 0; x < width; x++ {
+		level := uint8(255 - x*255/max(width-1, 1))
+		for y := 0; y < gradientHeight; y++ {
+			gradient.SetGray(x, y, color.Gray{Y: level})
+		}
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	writeTextLine(&buf, fmt.Sprintf("Gradient: %s, %d dots wide", config.DitheringAlgo, width), config)
+	writeTextLine(&buf, "Ticks every 10%: input level 255 (left) to 0 (right)", config)
+	buf.WriteByte(LF)
+
+	if err := writeGradient(&buf, gradient, config); err != nil {
+		logger().Error("Failed to write gradient", "error", err)
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	// Feed and cut if requested
+	writeFinalFeed(&buf, 2, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	return buf.Bytes()
+}
+
+// writeGradient dithers the gradient and writes it followed by the tick marks
+func writeGradient(buf *bytes.Buffer, gradient *image.Gray, config *Config) error {
+	dithered, err := ApplyDitheringConfig(gradient, config)
+	if err != nil {
+		return fmt.Errorf("failed to apply dithering: %w", err)
+	}
+	if err := writeImage(buf, dithered, config); err != nil {
+		return err
+	}
+
+	width := gradient.Bounds().Dx()
+	ticks := image.NewGray(image.Rect(0, 0, width, 12))
+	for y := 0; y < 12; y++ {
+		for x := 0; x < width; x++ {
+			ticks.SetGray(x, y, color.Gray{Y: 255})
+		}
+		for i := 0; i <= 10; i++ {
+			ticks.SetGray(i*(width-1)/10, y, color.Gray{Y: 0})
+		}
+	}
+	return writeImage(buf, ticks, config)
+}
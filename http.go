@@ -0,0 +1,106 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// defaultHTTPContentType is the content type of the requests sent by HTTPOutput
+const defaultHTTPContentType = "application/octet-stream"
+
+// HTTPOutput sends data to a print server in the body of an HTTP POST request.
+// Written data is buffered and sent as a single request on Close.
+type HTTPOutput struct {
+	// Content type of the request (default: application/octet-stream)
+	ContentType string
+
+	// Optional value of the Authorization header, e.g. "Bearer <token>"
+	Authorization string
+
+	// Additional request headers, overriding ContentType and Authorization
+	Headers map[string]string
+
+	// Client sending the request (default: http.DefaultClient)
+	Client *http.Client
+
+	url string
+	buf bytes.Buffer
+}
+
+// NewHTTPOutput creates a new HTTP output method posting to an http or https URL
+func NewHTTPOutput(rawURL string) (*HTTPOutput, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("invalid URL %q: scheme must be http or https", rawURL)
+	}
+	return &HTTPOutput{ContentType: defaultHTTPContentType, url: rawURL}, nil
+}
+
+// WebhookOutput posts print jobs to a webhook, such as the job endpoint of a cloud
+// printer relay. It behaves like HTTPOutput: the data is buffered and posted in a
+// single request on Close, and any non-2xx response is an error.
+type WebhookOutput struct {
+	*HTTPOutput
+}
+
+// NewWebhookOutput creates a new webhook output method posting to an http or https
+// URL with the given request headers, e.g. an API key header of the relay
+func NewWebhookOutput(rawURL string, headers map[string]string) (*WebhookOutput, error) {
+	output, err := NewHTTPOutput(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	output.Headers = headers
+	return &WebhookOutput{HTTPOutput: output}, nil
+}
+
+// Write buffers data until Close
+func (h *HTTPOutput) Write(data []byte) error {
+	h.buf.Write(data)
+	return nil
+}
+
+// Close posts the buffered data to the URL and fails unless the server responds
+// with a 2xx status
+func (h *HTTPOutput) Close() error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(h.buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	contentType := h.ContentType
+	if contentType == "" {
+		contentType = defaultHTTPContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	if h.Authorization != "" {
+		req.Header.Set("Authorization", h.Authorization)
+	}
+	for name, value := range h.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", h.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("print server responded with %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	logger().Debug("Data posted to print server", "url", h.url, "bytes", h.buf.Len(), "status", resp.Status)
+	h.buf.Reset()
+	return nil
+}
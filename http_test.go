@@ -0,0 +1,100 @@
+package escposimg
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordedRequest is a request received by the test server
+type recordedRequest struct {
+	method        string
+	header        http.Header
+	contentLength int64
+	body          []byte
+}
+
+// newRecordingServer starts a server that records each request and responds with status
+func newRecordingServer(t *testing.T, status int) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{method: r.Method, header: r.Header.Clone(), contentLength: r.ContentLength, body: body})
+		w.WriteHeader(status)
+		io.WriteString(w, "queue full\n")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPOutput(t *testing.T) {
+	server, requests := newRecordingServer(t, http.StatusAccepted)
+	output, err := NewHTTPOutput(server.URL + "/print")
+	if err != nil {
+		t.Fatalf("NewHTTPOutput() error = %v", err)
+	}
+	output.Authorization = "Bearer secret"
+	output.Headers = map[string]string{"X-Printer": "kitchen"}
+
+	job := []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 1, 0, 0xFF, LF, GS, 'V', 1}
+	output.Write(job[:3])
+	output.Write(job[3:])
+	if len(*requests) != 0 {
+		t.Fatalf("request sent before Close")
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.method)
+	}
+	if !bytes.Equal(req.body, job) {
+		t.Errorf("body = % X, want % X", req.body, job)
+	}
+	if req.contentLength != int64(len(job)) {
+		t.Errorf("Content-Length = %d, want %d", req.contentLength, len(job))
+	}
+	headers := map[string]string{
+		"Content-Type":  "application/octet-stream",
+		"Authorization": "Bearer secret",
+		"X-Printer":     "kitchen",
+	}
+	for name, want := range headers {
+		if got := req.header.Get(name); got != want {
+			t.Errorf("header %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestHTTPOutputErrorStatus(t *testing.T) {
+	server, requests := newRecordingServer(t, http.StatusServiceUnavailable)
+	output, err := NewWebhookOutput(server.URL, map[string]string{"Content-Type": "application/vnd.escpos"})
+	if err != nil {
+		t.Fatalf("NewWebhookOutput() error = %v", err)
+	}
+	output.Write([]byte{ESC, '@'})
+	err = output.Close()
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "queue full") {
+		t.Errorf("Close() error = %v, want the status and response body", err)
+	}
+	if got := (*requests)[0].header.Get("Content-Type"); got != "application/vnd.escpos" {
+		t.Errorf("Content-Type = %q, want the webhook header to override the default", got)
+	}
+}
+
+func TestNewHTTPOutputInvalidURL(t *testing.T) {
+	for _, url := range []string{"ftp://printer/queue", "://", "printer:9100"} {
+		if _, err := NewHTTPOutput(url); err == nil {
+			t.Errorf("NewHTTPOutput(%q) returned no error", url)
+		}
+	}
+}
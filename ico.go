@@ -0,0 +1,178 @@
+package escposimg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// icoHeader is the magic prefix of ICO files (reserved 0, type 1 = icon)
+const icoHeader = "\x00\x00\x01\x00"
+
+// icoEntry is a directory entry of an ICO file
+type icoEntry struct {
+	width, height int
+	bitCount      int
+	size, offset  int
+}
+
+// decodeICO decodes the largest image of an ICO file
+func decodeICO(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := largestICOEntry(data)
+	if err != nil {
+		return nil, err
+	}
+	if entry.offset+entry.size > len(data) {
+		return nil, errors.New("ico: image data out of range")
+	}
+	frame := data[entry.offset : entry.offset+entry.size]
+
+	// Frames are either embedded PNG files or BMP data without file header
+	if bytes.HasPrefix(frame, []byte("\x89PNG\r\n\x1a\n")) {
+		return png.Decode(bytes.NewReader(frame))
+	}
+	return decodeDIB(frame)
+}
+
+// decodeICOConfig returns the dimensions of the largest image of an ICO file
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	entry, err := largestICOEntry(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: entry.width, Height: entry.height}, nil
+}
+
+// largestICOEntry returns the directory entry with the highest resolution,
+// preferring the higher color depth between entries of equal size
+func largestICOEntry(data []byte) (icoEntry, error) {
+	if len(data) < 6 || string(data[:4]) != icoHeader {
+		return icoEntry{}, errors.New("ico: invalid header")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return icoEntry{}, errors.New("ico: invalid image directory")
+	}
+
+	var best icoEntry
+	for i := 0; i < count; i++ {
+		e := data[6+16*i : 6+16*(i+1)]
+		entry := icoEntry{
+			width:    int(e[0]),
+			height:   int(e[1]),
+			bitCount: int(binary.LittleEndian.Uint16(e[6:8])),
+			size:     int(binary.LittleEndian.Uint32(e[8:12])),
+			offset:   int(binary.LittleEndian.Uint32(e[12:16])),
+		}
+		// A size of 0 means 256 pixels
+		if entry.width == 0 {
+			entry.width = 256
+		}
+		if entry.height == 0 {
+			entry.height = 256
+		}
+
+		area, bestArea := entry.width*entry.height, best.width*best.height
+		if area > bestArea || (area == bestArea && entry.bitCount > best.bitCount) {
+			best = entry
+		}
+	}
+	return best, nil
+}
+
+// decodeDIB decodes the BMP data of an ICO frame: a BITMAPINFOHEADER followed by
+// an optional palette, the bottom-up color bitmap and a 1 bit transparency mask
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errors.New("ico: truncated bitmap header")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	// The height covers both the color bitmap and the mask
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+	if width <= 0 || height <= 0 || headerSize < 40 || headerSize > len(data) {
+		return nil, fmt.Errorf("ico: invalid bitmap dimensions %dx%d", width, height)
+	}
+
+	var palette []color.NRGBA
+	if bitCount <= 8 {
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bitCount
+		}
+		if headerSize+4*colorsUsed > len(data) {
+			return nil, errors.New("ico: truncated palette")
+		}
+		for i := 0; i < colorsUsed; i++ {
+			c := data[headerSize+4*i:]
+			palette = append(palette, color.NRGBA{R: c[2], G: c[1], B: c[0], A: 255})
+		}
+	} else if bitCount != 24 && bitCount != 32 {
+		return nil, fmt.Errorf("ico: unsupported bit depth %d", bitCount)
+	}
+
+	// Rows are padded to multiples of 4 bytes
+	stride := (width*bitCount + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	pixels := headerSize + 4*len(palette)
+	mask := pixels + stride*height
+	hasMask := mask+maskStride*height <= len(data)
+	if mask > len(data) {
+		return nil, errors.New("ico: truncated bitmap data")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := data[pixels+(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 255}
+			default:
+				bit := x * bitCount
+				index := int(row[bit/8]>>(8-bitCount-bit%8)) & (1<<bitCount - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+
+			// Images below 32 bits use the mask for transparency
+			if bitCount != 32 && hasMask {
+				maskRow := data[mask+(height-1-y)*maskStride:]
+				if maskRow[x/8]&(0x80>>uint(x%8)) != 0 {
+					c.A = 0
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
+
+// flattenOnWhite composites an image with transparency onto white paper, so
+// transparent areas do not print black
+func flattenOnWhite(img image.Image) image.Image {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+	return flat
+}
@@ -1,15 +1,20 @@
 package escposimg
 
 import (
+	"bufio"
 	"fmt"
 	"image"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/webp"
 )
 
-// LoadImage loads an image from the specified file path.
-// Supports PNG and JPEG formats.
+// LoadImage loads an image from the specified file path (see LoadImageReader)
 func LoadImage(imagePath string) (image.Image, error) {
 	file, err := os.Open(imagePath)
 	if err != nil {
@@ -17,23 +22,59 @@ func LoadImage(imagePath string) (image.Image, error) {
 	}
 	defer file.Close()
 
+	return LoadImageReader(file)
+}
+
+// LoadImageReader decodes an image from a reader, e.g. data downloaded over HTTP.
+// Supports PNG, JPEG, GIF, BMP, WebP (lossy and lossless) and ICO formats. For
+// ICO files the largest image is used, for animated GIFs the first frame.
+// Transparent areas of icons and GIFs are printed white.
+func LoadImageReader(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+
+	// GIFs are decoded with all frames, so that dropped frames of animations can be
+	// logged without decoding the data a second time
+	if header, _ := br.Peek(len(gifHeader)); string(header) == gifHeader {
+		return decodeGIF(br)
+	}
+
 	// Decode the image
-	img, format, err := image.Decode(file)
+	img, format, err := image.Decode(br)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
 	// Log the detected format for debugging
 	switch format {
-	case "png", "jpeg":
+	case "png", "jpeg", "bmp", "webp":
 		// Supported formats
+	case "ico":
+		// Icons rely on transparency for their background
+		img = flattenOnWhite(img)
 	default:
-		return nil, fmt.Errorf("unsupported image format: %s (supported: PNG, JPEG)", format)
+		return nil, fmt.Errorf("unsupported image format: %s (supported: PNG, JPEG, GIF, BMP, WebP, ICO)", format)
 	}
 
 	return img, nil
 }
 
+// gifHeader starts the data of every GIF image
+const gifHeader = "GIF8"
+
+// decodeGIF decodes a GIF image and returns its first frame on white
+func decodeGIF(r io.Reader) (image.Image, error) {
+	all, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	// Only the first frame is printed
+	if len(all.Image) > 1 {
+		logger().Debug("Using the first frame of an animated GIF", "dropped_frames", len(all.Image)-1)
+	}
+	return flattenOnWhite(all.Image[0]), nil
+}
+
 // SaveDebugImage saves an image to the specified path for debugging purposes
 func SaveDebugImage(img image.Image, path string) error {
 	file, err := os.Create(path)
@@ -54,4 +95,8 @@ func init() {
 	// Register image formats
 	image.RegisterFormat("png", "png", png.Decode, png.DecodeConfig)
 	image.RegisterFormat("jpeg", "jpeg", jpeg.Decode, jpeg.DecodeConfig)
+	image.RegisterFormat("gif", "GIF8?a", gif.Decode, gif.DecodeConfig)
+	image.RegisterFormat("bmp", "BM????\x00\x00\x00\x00", bmp.Decode, bmp.DecodeConfig)
+	image.RegisterFormat("webp", "RIFF????WEBPVP8", webp.Decode, webp.DecodeConfig)
+	image.RegisterFormat("ico", icoHeader, decodeICO, decodeICOConfig)
 }
@@ -0,0 +1,100 @@
+package escposimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/bmp"
+)
+
+// encodeGIF returns an animated GIF whose first frame is black with a transparent
+// top-left pixel and whose other frames are white
+func encodeGIF(t *testing.T, width, height, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White, color.Transparent}
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		if i > 0 {
+			for j := range frame.Pix {
+				frame.Pix[j] = 1
+			}
+		} else {
+			frame.SetColorIndex(0, 0, 2)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("gif.EncodeAll() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadImageReaderFormats(t *testing.T) {
+	var bmpData bytes.Buffer
+	if err := bmp.Encode(&bmpData, solidImage(24, 10, 0)); err != nil {
+		t.Fatalf("bmp.Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		width       int
+		height      int
+		transparent bool
+	}{
+		{"GIF", encodeGIF(t, 20, 12, 1), 20, 12, true},
+		{"animated GIF", encodeGIF(t, 20, 12, 3), 20, 12, true},
+		{"BMP", bmpData.Bytes(), 24, 10, false},
+	}
+	for _, tt := range tests {
+		img, err := LoadImageReader(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: LoadImageReader() error = %v", tt.name, err)
+		}
+		if got := img.Bounds(); got.Empty() || got.Dx() != tt.width || got.Dy() != tt.height {
+			t.Errorf("%s: bounds = %v, want %dx%d", tt.name, got, tt.width, tt.height)
+		}
+
+		// The first frame is used, with transparency printed white
+		if got := grayAt(img, 5, 5); got != 0 {
+			t.Errorf("%s: pixel (5,5) = %d, want black", tt.name, got)
+		}
+		if tt.transparent {
+			if got := grayAt(img, 0, 0); got != 255 {
+				t.Errorf("%s: transparent pixel (0,0) = %d, want white", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestWebPPipeline(t *testing.T) {
+	// The fixtures are copied from the testdata of golang.org/x/image
+	for _, name := range []string{"lossy", "lossless"} {
+		path := filepath.Join("testdata", name+".webp")
+		config := DefaultConfig()
+		img, err := PrepareImage(path, config)
+		if err != nil {
+			t.Fatalf("%s: PrepareImage() error = %v", name, err)
+		}
+		if got := img.Bounds().Dx(); got != config.CalculatePixelWidth() {
+			t.Errorf("%s: width = %d, want the paper width %d", name, got, config.CalculatePixelWidth())
+		}
+
+		// Dithering leaves only black and white pixels
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if gray := grayAt(img, x, y); gray != 0 && gray != 255 {
+					t.Fatalf("%s: pixel (%d,%d) = %d, want black or white", name, x, y, gray)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ProcessLabel prints an image (e.g. a logo) followed by a barcode as a single job,
+// separated by config.LabelGapDots of blank paper. See GenerateLabelCommands.
+func ProcessLabel(imagePath string, barcodeData string, barcodeType int, config *Config, output OutputMethod) error {
+	data, err := GenerateLabelCommands(imagePath, barcodeData, barcodeType, config)
+	if err != nil {
+		return err
+	}
+
+	if err := writeCommands(output, data, config.InitDelay); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	logger().Info("Label processing completed successfully")
+	return nil
+}
+
+// GenerateLabelCommands generates the commands for a label consisting of the dithered
+// image, a gap of config.LabelGapDots and a barcode, with a single printer
+// initialization and, if a cut is configured, a single cut at the end.
+//
+// barcodeType is the GS k barcode system from 65 (UPC-A) to 73 (CODE128); the
+// barcode is printed with config.BarcodeHeightDots and config.BarcodeHRI.
+func GenerateLabelCommands(imagePath string, barcodeData string, barcodeType int, config *Config) ([]byte, error) {
+	img, err := PrepareImage(imagePath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	if config.DebugText != "" {
+		writeTextLine(&buf, config.DebugText, config)
+	}
+
+	if err := writeImage(&buf, img, config); err != nil {
+		return nil, err
+	}
+
+	writeFeedDots(&buf, config.LabelGapDots)
+
+	if err := writeBarcodeConfig(&buf, BarcodeType(barcodeType), barcodeData, config); err != nil {
+		return nil, err
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	writeFinalFeed(&buf, 3, config)
+
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Label command generation completed", "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
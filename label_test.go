@@ -0,0 +1,32 @@
+package escposimg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateLabelCommandsBarcode(t *testing.T) {
+	path := writePNG(t, solidImage(32, 16, 0))
+
+	tests := []struct {
+		name   string
+		config func(*Config)
+		want   []byte
+	}{
+		{"code128 default", func(c *Config) {},
+			[]byte{GS, 'H', 2, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}},
+		{"height and hri", func(c *Config) { c.BarcodeHeightDots = 80; c.BarcodeHRI = HRINone },
+			[]byte{GS, 'h', 80, GS, 'H', 0, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+		data, err := GenerateLabelCommands(path, "ABC", int(BarcodeCODE128), config)
+		if err != nil {
+			t.Fatalf("%s: GenerateLabelCommands() error = %v", tt.name, err)
+		}
+		if !bytes.Contains(data, tt.want) {
+			t.Errorf("%s: output does not contain % X", tt.name, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package escposimg
+
+import "log/slog"
+
+// libLogger is the logger used by the library, nil means slog.Default()
+var libLogger *slog.Logger
+
+// SetLogger sets the logger used for all log output of the library.
+//
+// By default the library logs through slog.Default(), which writes to stderr.
+// When printing through StdoutOutput, any log line written to stdout would end up
+// in the printer data stream, so applications that reconfigure the default logger
+// should pass a logger writing to stderr (or elsewhere) here. Passing nil restores
+// the default behaviour.
+func SetLogger(l *slog.Logger) {
+	libLogger = l
+}
+
+// logger returns the logger used by the library
+func logger() *slog.Logger {
+	if libLogger != nil {
+		return libLogger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,65 @@
+package escposimg
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+// captureStdout runs fn with os.Stdout redirected and returns what was written
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestStdoutOutputHasNoLogLines(t *testing.T) {
+	path := writePNG(t, solidImage(64, 32, 100))
+	config := DefaultConfig()
+	want, err := GenerateImageCommands(path, config)
+	if err != nil {
+		t.Fatalf("GenerateImageCommands() error = %v", err)
+	}
+
+	var logs bytes.Buffer
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+	}{
+		{"default logger", nil},
+		{"debug logger", slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))},
+	}
+	defer SetLogger(nil)
+	for _, tt := range tests {
+		SetLogger(tt.logger)
+		var processErr error
+		got := captureStdout(t, func() {
+			processErr = ProcessImage(path, config, NewStdoutOutput())
+		})
+		if processErr != nil {
+			t.Fatalf("%s: ProcessImage() error = %v", tt.name, processErr)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: stdout holds %d bytes, want exactly the %d ESC/POS bytes", tt.name, len(got), len(want))
+		}
+	}
+	if logs.Len() == 0 {
+		t.Errorf("debug logger received no log lines")
+	}
+}
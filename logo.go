@@ -0,0 +1,34 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PrintStoredLogo generates the commands to print a graphic that was previously
+// stored in the printer's NV memory under the given key code, followed by the
+// usual paper feed (see Config.FeedLinesBeforeCut) and an optional cut (see
+// Config.CutType).
+//
+// No image data is sent, so a pre-uploaded logo can be printed with only a
+// handful of bytes per receipt.
+func PrintStoredLogo(keyCode [2]byte, config *Config) ([]byte, error) {
+	for _, kc := range keyCode {
+		if kc < 32 || kc > 126 {
+			return nil, fmt.Errorf("invalid key code %q: bytes must be printable ASCII (32-126)", keyCode[:])
+		}
+	}
+
+	var buf bytes.Buffer
+
+	// GS ( L pL pH m fn kc1 kc2 x y: print NV graphics data (fn=69) at 1x scale
+	buf.Write([]byte{GS, '(', 'L', 6, 0, 48, 69, keyCode[0], keyCode[1], 1, 1})
+
+	writeFinalFeed(&buf, 3, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Stored logo print command generated", "key_code", string(keyCode[:]), "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,40 @@
+package escposimg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintStoredLogoFeedAndCut(t *testing.T) {
+	logo := []byte{GS, '(', 'L', 6, 0, 48, 69, 'A', '1', 1, 1}
+
+	tests := []struct {
+		name   string
+		config func(*Config)
+		tail   []byte
+	}{
+		{"default", func(c *Config) {}, []byte{LF, LF, LF}},
+		{"cut", func(c *Config) { c.CutType = CutFull }, []byte{LF, LF, LF, GS, 'V', 0}},
+		{"feed lines", func(c *Config) { lines := 1; c.FeedLinesBeforeCut = &lines; c.CutType = CutPartial }, []byte{LF, GS, 'V', 1}},
+		{"no feed lines", func(c *Config) { lines := 0; c.FeedLinesBeforeCut = &lines; c.CutType = CutFull }, []byte{GS, 'V', 0}},
+		{"skip final feed", func(c *Config) { c.SkipFinalFeed = true }, nil},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+		data, err := PrintStoredLogo([2]byte{'A', '1'}, config)
+		if err != nil {
+			t.Fatalf("%s: PrintStoredLogo() error = %v", tt.name, err)
+		}
+		want := append(append([]byte{}, logo...), tt.tail...)
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: PrintStoredLogo() = % X, want % X", tt.name, data, want)
+		}
+	}
+}
+
+func TestPrintStoredLogoInvalidKey(t *testing.T) {
+	if _, err := PrintStoredLogo([2]byte{'A', 0x7F}, DefaultConfig()); err == nil {
+		t.Errorf("PrintStoredLogo() with a non-printable key code returned no error")
+	}
+}
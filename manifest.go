@@ -0,0 +1,203 @@
+package escposimg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Manifest describes a multi-part receipt as an ordered list of elements.
+//
+// Example (JSON):
+//
+//	{
+//	  "paper_width_mm": 80,
+//	  "dithering": "atkinson",
+//	  "cut": true,
+//	  "elements": [
+//	    {"type": "image", "path": "logo.png"},
+//	    {"type": "text", "text": "Order #1234"},
+//	    {"type": "feed", "lines": 2},
+//	    {"type": "image", "path": "map.jpg"}
+//	  ]
+//	}
+type Manifest struct {
+	// Paper width in millimeters (default: 80mm)
+	PaperWidthMM int `json:"paper_width_mm"`
+
+	// Printer DPI (default: 203 DPI)
+	DPI int `json:"dpi"`
+
+	// Dithering algorithm name as printed by DitheringType.String (default: floyd-steinberg)
+	Dithering string `json:"dithering"`
+
+	// Print mode name as printed by PrintMode.String (default: raster)
+	PrintMode string `json:"print_mode"`
+
+	// Cut the paper after the last element
+	Cut bool `json:"cut"`
+
+	// Elements of the receipt in print order
+	Elements []ManifestElement `json:"elements"`
+}
+
+// ManifestElement is a single part of a manifest receipt
+type ManifestElement struct {
+	// Element type: "image", "text", "separator", "feed" or "cut"
+	Type string `json:"type"`
+
+	// Image path for "image" elements, relative to the manifest file
+	Path string `json:"path,omitempty"`
+
+	// Text for "text" elements
+	Text string `json:"text,omitempty"`
+
+	// Number of line feeds for "feed" elements (default: 1)
+	Lines int `json:"lines,omitempty"`
+
+	// Style for "separator" elements as printed by SeparatorStyle.String (default: solid)
+	Style string `json:"style,omitempty"`
+}
+
+// LoadManifest reads and parses a JSON manifest file
+func LoadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ProcessManifest renders the receipt described by a manifest file and sends it to the output
+// as a single ESC/POS stream with one initialization and an optional cut at the end.
+func ProcessManifest(manifestPath string, output OutputMethod) error {
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := GenerateManifestCommands(manifest, filepath.Dir(manifestPath))
+	if err != nil {
+		return err
+	}
+
+	if err := output.Write(data); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	logger().Info("Manifest processing completed successfully", "elements", len(manifest.Elements))
+	return nil
+}
+
+// GenerateManifestCommands generates the ESC/POS commands for a manifest.
+// Relative image paths are resolved against baseDir.
+func GenerateManifestCommands(manifest *Manifest, baseDir string) ([]byte, error) {
+	config, err := manifest.config()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	for i, element := range manifest.Elements {
+		if err := writeManifestElement(&buf, element, config, baseDir); err != nil {
+			return nil, fmt.Errorf("manifest element %d (%s): %w", i, element.Type, err)
+		}
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	if manifest.Cut {
+		writeFinalFeed(&buf, 3, config)
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Manifest command generation completed", "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
+
+// writeManifestElement writes the commands for a single manifest element
+func writeManifestElement(buf *bytes.Buffer, element ManifestElement, config *Config, baseDir string) error {
+	switch element.Type {
+	case "image":
+		path := element.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		img, err := PrepareImage(path, config)
+		if err != nil {
+			return err
+		}
+		return writeImage(buf, img, config)
+	case "text":
+		writeTextLine(buf, element.Text, config)
+	case "separator":
+		style, err := ParseSeparatorStyle(element.Style)
+		if err != nil {
+			return err
+		}
+		width := config.CalculatePixelWidth()
+		if width <= 0 {
+			return fmt.Errorf("invalid printable width of %d dots", width)
+		}
+		return writeSeparator(buf, style, width, config)
+	case "feed":
+		lines := max(element.Lines, 1)
+		for i := 0; i < lines; i++ {
+			buf.WriteByte(LF)
+		}
+	case "cut":
+		writeFinalFeed(buf, 3, config)
+		writePaperCut(buf, config)
+	default:
+		return fmt.Errorf("unsupported element type %q", element.Type)
+	}
+	return nil
+}
+
+// config builds the processing configuration described by the manifest
+func (m *Manifest) config() (*Config, error) {
+	config := DefaultConfig()
+	if m.PaperWidthMM > 0 {
+		config.PaperWidthMM = m.PaperWidthMM
+	}
+	if m.DPI > 0 {
+		config.DPI = m.DPI
+	}
+
+	if m.Dithering != "" {
+		found := false
+		for _, algo := range DitheringTypes() {
+			if algo.String() == m.Dithering {
+				config.DitheringAlgo = algo
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown dithering algorithm in manifest: %s", m.Dithering)
+		}
+	}
+
+	switch m.PrintMode {
+	case "", PrintModeRaster.String():
+		config.PrintMode = PrintModeRaster
+	case PrintModeBitImage.String():
+		config.PrintMode = PrintModeBitImage
+	default:
+		return nil, fmt.Errorf("unknown print mode in manifest: %s", m.PrintMode)
+	}
+
+	return config, nil
+}
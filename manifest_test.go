@@ -0,0 +1,47 @@
+package escposimg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestManifestCutFeed(t *testing.T) {
+	lines := func(n int) *int { return &n }
+	cut := []byte{GS, 'V', 1}
+
+	tests := []struct {
+		name  string
+		lines *int
+		want  int
+	}{
+		{"default", nil, 3},
+		{"zero", lines(0), 0},
+		{"one", lines(1), 1},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.FeedLinesBeforeCut = tt.lines
+		config.CutType = CutPartial
+
+		var buf bytes.Buffer
+		if err := writeManifestElement(&buf, ManifestElement{Type: "cut"}, config, ""); err != nil {
+			t.Fatalf("%s: writeManifestElement() error = %v", tt.name, err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), cut) || trailingLineFeeds(buf.Bytes(), cut) != tt.want || buf.Len() != tt.want+len(cut) {
+			t.Errorf("%s: cut element = % X, want %d line feeds and the cut", tt.name, buf.Bytes(), tt.want)
+		}
+	}
+}
+
+func TestGenerateManifestCommandsCut(t *testing.T) {
+	manifest := &Manifest{Cut: true, Elements: []ManifestElement{{Type: "text", Text: "x"}}}
+	data, err := GenerateManifestCommands(manifest, "")
+	if err != nil {
+		t.Fatalf("GenerateManifestCommands() error = %v", err)
+	}
+	// The text line feed, three feed lines and the cut
+	want := []byte{'x', LF, LF, LF, LF, GS, 'V', 1}
+	if !bytes.HasSuffix(data, want) {
+		t.Errorf("manifest ends with % X, want % X", data[max(len(data)-len(want), 0):], want)
+	}
+}
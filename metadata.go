@@ -0,0 +1,95 @@
+package escposimg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngText returns the value of the PNG text chunk (tEXt, zTXt or iTXt) with the
+// given keyword. It reports false for other formats, missing keywords and
+// malformed chunks.
+func pngText(data []byte, keyword string) (string, bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return "", false
+	}
+
+	// Each chunk is a 4 byte length, a 4 byte type, the data and a 4 byte CRC
+	pos := len(pngSignature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		if length < 0 || pos+12+length > len(data) {
+			return "", false
+		}
+		chunk := data[pos+8 : pos+8+length]
+		pos += 12 + length
+
+		key, rest, ok := bytes.Cut(chunk, []byte{0})
+		if !ok || string(key) != keyword {
+			if chunkType == "IEND" {
+				break
+			}
+			continue
+		}
+		switch chunkType {
+		case "tEXt":
+			return latin1ToString(rest), true
+		case "zTXt":
+			// Compression method (0 = zlib) followed by the compressed text
+			if len(rest) < 1 || rest[0] != 0 {
+				return "", false
+			}
+			text, err := inflate(rest[1:])
+			if err != nil {
+				return "", false
+			}
+			return latin1ToString(text), true
+		case "iTXt":
+			// Compression flag and method, language tag, translated keyword, UTF-8 text
+			if len(rest) < 2 {
+				return "", false
+			}
+			compressed := rest[0] == 1
+			_, rest, ok = bytes.Cut(rest[2:], []byte{0})
+			if !ok {
+				return "", false
+			}
+			_, text, ok := bytes.Cut(rest, []byte{0})
+			if !ok {
+				return "", false
+			}
+			if compressed {
+				var err error
+				if text, err = inflate(text); err != nil {
+					return "", false
+				}
+			}
+			return string(text), true
+		}
+	}
+	return "", false
+}
+
+// inflate decompresses zlib data
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// latin1ToString decodes ISO 8859-1 text, the encoding of tEXt and zTXt chunks
+func latin1ToString(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
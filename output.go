@@ -1,12 +1,26 @@
 package escposimg
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
-// StdoutOutput writes data to stdout
+// StdoutOutput writes data to stdout.
+//
+// The library never logs to stdout; see SetLogger when the default slog logger
+// has been redirected, as log lines on stdout would corrupt the printer data.
 type StdoutOutput struct{}
 
 // NewStdoutOutput creates a new stdout output method
@@ -27,22 +41,112 @@ func (s *StdoutOutput) Close() error {
 
 // NetworkOutput writes data to a network connection
 type NetworkOutput struct {
-	conn net.Conn
+	conn    net.Conn
+	address string
+	config  NetworkOutputConfig
+}
+
+// dialContext connects to a network printer (replaced in tests)
+var dialContext = (&net.Dialer{}).DialContext
+
+// NetworkOutputConfig holds the connection options of a NetworkOutput
+type NetworkOutputConfig struct {
+	// Number of attempts to connect and to send each write (zero or one tries
+	// once). A failed write reconnects and resends all data of the write, so the
+	// printer may receive part of it twice.
+	MaxAttempts int
+
+	// Pause between attempts, e.g. while the printer wakes up from sleep
+	RetryDelay time.Duration
 }
 
 // NewNetworkOutput creates a new network output method
 func NewNetworkOutput(address string) (*NetworkOutput, error) {
-	conn, err := net.Dial("tcp", address)
+	return NewNetworkOutputWithConfig(address, NetworkOutputConfig{})
+}
+
+// NewNetworkOutputWithConfig creates a new network output method that retries
+// connecting and writing as configured
+func NewNetworkOutputWithConfig(address string, config NetworkOutputConfig) (*NetworkOutput, error) {
+	n := &NetworkOutput{address: address, config: config}
+	err := n.retry(context.Background(), func(int) error {
+		conn, err := dialContext(context.Background(), "tcp", address)
+		if err != nil {
+			return err
+		}
+		n.conn = conn
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
-	return &NetworkOutput{conn: conn}, nil
+	return n, nil
 }
 
 // Write writes data to the network connection
 func (n *NetworkOutput) Write(data []byte) error {
-	_, err := n.conn.Write(data)
-	return err
+	return n.WriteContext(context.Background(), data)
+}
+
+// WriteContext writes data to the network connection, honoring the deadline of
+// ctx and interrupting the write when ctx is canceled
+func (n *NetworkOutput) WriteContext(ctx context.Context, data []byte) error {
+	return n.retry(ctx, func(attempt int) error {
+		if attempt > 1 {
+			// The connection may be broken after a failed write, so start over
+			n.conn.Close()
+			conn, err := dialContext(ctx, "tcp", n.address)
+			if err != nil {
+				return fmt.Errorf("failed to reconnect to %s: %w", n.address, err)
+			}
+			n.conn = conn
+		}
+		return n.write(ctx, data)
+	})
+}
+
+// write writes data to the current connection (see WriteContext)
+func (n *NetworkOutput) write(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := n.conn.SetWriteDeadline(deadline); err != nil {
+			return fmt.Errorf("failed to set write deadline: %w", err)
+		}
+	}
+	defer n.conn.SetWriteDeadline(time.Time{})
+
+	// Unblock a pending write once ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		n.conn.SetWriteDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := n.conn.Write(data); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	return nil
+}
+
+// retry runs op up to config.MaxAttempts times (at least once) until it succeeds,
+// pausing config.RetryDelay between attempts. It stops early when ctx is done.
+func (n *NetworkOutput) retry(ctx context.Context, op func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := op(attempt)
+		if err == nil || attempt >= n.config.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		logger().Warn("Network printer operation failed, retrying", "address", n.address, "attempt", attempt, "error", err)
+		select {
+		case <-time.After(n.config.RetryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // Close closes the network connection
@@ -50,9 +154,89 @@ func (n *NetworkOutput) Close() error {
 	return n.conn.Close()
 }
 
+// ErrQueryUnsupported is returned by NetworkOutput.QueryWidth when the printer
+// does not answer the query or its model is unknown
+var ErrQueryUnsupported = errors.New("printer does not support width detection")
+
+// queryTimeout bounds the wait for the response to a printer status query
+const queryTimeout = 2 * time.Second
+
+// modelWidths maps printer model name prefixes, as reported by GS I 67, to the
+// maximum print width in dots on 80mm paper
+var modelWidths = []struct {
+	prefix string
+	dots   int
+}{
+	{"TM-T88", 512},
+	{"TM-T70", 512},
+	{"TM-T20", 576},
+	{"TM-T82", 576},
+	{"TM-m30", 576},
+}
+
+// QueryWidth asks the printer for its model name (GS I 67, supported by Epson
+// TM printers and many compatibles) and returns the maximum print width in dots
+// of known models, for use as Config.WidthDots. As ESC/POS has no command that
+// reports the width itself, ErrQueryUnsupported is returned for printers that
+// do not answer within two seconds and for unknown models.
+func (n *NetworkOutput) QueryWidth() (int, error) {
+	if err := n.conn.SetDeadline(time.Now().Add(queryTimeout)); err != nil {
+		return 0, fmt.Errorf("failed to set query deadline: %w", err)
+	}
+	defer n.conn.SetDeadline(time.Time{})
+
+	if _, err := n.conn.Write([]byte{GS, 'I', 67}); err != nil {
+		return 0, fmt.Errorf("failed to send model query: %w", err)
+	}
+
+	model, err := readModelName(n.conn)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return 0, fmt.Errorf("%w: no response to model query", ErrQueryUnsupported)
+		}
+		return 0, fmt.Errorf("failed to read model name: %w", err)
+	}
+	logger().Debug("Printer reported model", "model", model)
+
+	for _, m := range modelWidths {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.dots, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown model %q", ErrQueryUnsupported, model)
+}
+
+// readModelName reads a GS I 67 response: a 0x5F header, the model name and a NUL.
+// Bytes before the header (e.g. automatic status messages) are skipped.
+func readModelName(r io.Reader) (string, error) {
+	var name []byte
+	started := false
+	b := make([]byte, 1)
+	for len(name) < 80 {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		switch {
+		case !started:
+			started = b[0] == 0x5F
+		case b[0] == 0:
+			return string(name), nil
+		default:
+			name = append(name, b[0])
+		}
+	}
+	return "", errors.New("model name is not terminated")
+}
+
 // FileOutput writes data to a file
 type FileOutput struct {
 	file *os.File
+	path string
+
+	// Retry settings for transient filesystem errors (see NewFileOutputWithRetry)
+	attempts int
+	backoff  time.Duration
 }
 
 // NewFileOutput creates a new file output method
@@ -61,16 +245,388 @@ func NewFileOutput(filePath string) (*FileOutput, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file %s: %w", filePath, err)
 	}
-	return &FileOutput{file: file}, nil
+	return &FileOutput{file: file, path: filePath}, nil
+}
+
+// NewFileOutputWithRetry creates a new file output method that retries creating the
+// file and writing to it up to attempts times, waiting backoff between attempts, when
+// a transient filesystem error occurs (e.g. a stale NFS handle or an interrupted call).
+// Other errors are returned immediately.
+func NewFileOutputWithRetry(filePath string, attempts int, backoff time.Duration) (*FileOutput, error) {
+	var file *os.File
+	err := retryTransient(attempts, backoff, func() error {
+		var err error
+		file, err = os.Create(filePath)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", filePath, err)
+	}
+	return &FileOutput{file: file, path: filePath, attempts: attempts, backoff: backoff}, nil
 }
 
 // Write writes data to the file
 func (f *FileOutput) Write(data []byte) error {
-	_, err := f.file.Write(data)
-	return err
+	return retryTransient(f.attempts, f.backoff, func() error {
+		// Only the part not yet written is retried
+		n, err := f.file.Write(data)
+		data = data[n:]
+		return err
+	})
 }
 
 // Close closes the file
 func (f *FileOutput) Close() error {
 	return f.file.Close()
 }
+
+// metaSuffix is appended to the path of an output file to name its metadata sidecar
+const metaSuffix = ".meta"
+
+// DeviceOutput writes data to a printer device file, e.g. /dev/usb/lp0 on Linux.
+// For serial ports with pacing or flow control use SerialOutput instead.
+type DeviceOutput struct {
+	device *os.File
+}
+
+// NewDeviceOutput opens a device file as output method. Unlike NewFileOutput it
+// does not create or truncate the path, so a missing device is reported as an error.
+func NewDeviceOutput(devicePath string) (*DeviceOutput, error) {
+	device, err := os.OpenFile(devicePath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open device %s: %w", devicePath, err)
+	}
+	return &DeviceOutput{device: device}, nil
+}
+
+// Write writes data to the device
+func (d *DeviceOutput) Write(data []byte) error {
+	_, err := d.device.Write(data)
+	return err
+}
+
+// Close closes the device
+func (d *DeviceOutput) Close() error {
+	return d.device.Close()
+}
+
+// writeMetaSidecar writes the metadata file for the ESC/POS data written to a file
+// output. Other outputs have no place for it, which is logged as a warning.
+func writeMetaSidecar(output OutputMethod, source string, config *Config, data []byte) error {
+	fileOutput, ok := output.(*FileOutput)
+	if !ok {
+		return config.warn("FileComment is only supported for file output", "output", fmt.Sprintf("%T", output))
+	}
+	if source == "" {
+		source = "-"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "file: %s\n", filepath.Base(fileOutput.path))
+	fmt.Fprintf(&sb, "source: %s\n", source)
+	fmt.Fprintf(&sb, "created: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "generator: escposimg %s\n", Version())
+	fmt.Fprintf(&sb, "size: %d bytes\n", len(data))
+	fmt.Fprintf(&sb, "sha256: %x\n", sha256.Sum256(data))
+	fmt.Fprintf(&sb, "width: %d dots (%d mm at %d DPI)\n", config.CalculatePixelWidth(), config.PaperWidthMM, config.HorizontalDPI())
+	fmt.Fprintf(&sb, "print_mode: %s\n", config.PrintMode)
+	fmt.Fprintf(&sb, "dithering: %s\n", config.DitheringAlgo)
+	fmt.Fprintf(&sb, "cut: %s\n", config.cutType())
+
+	metaPath := fileOutput.path + metaSuffix
+	if err := os.WriteFile(metaPath, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write metadata file %s: %w", metaPath, err)
+	}
+	logger().Debug("Metadata file written", "path", metaPath)
+	return nil
+}
+
+// contextOutput is implemented by outputs whose writes can be aborted through a
+// context, such as NetworkOutput
+type contextOutput interface {
+	WriteContext(ctx context.Context, data []byte) error
+}
+
+// flusher is implemented by outputs that hold back written data, such as BufferedOutput
+type flusher interface {
+	Flush() error
+}
+
+// writeCommands writes ESC/POS data to an output. With a positive initDelay and
+// data starting with ESC @, the reset is written on its own and the rest follows
+// after the delay, giving the printer time to reset before receiving data.
+func writeCommands(output OutputMethod, data []byte, initDelay time.Duration) error {
+	return writeCommandsContext(context.Background(), output, data, initDelay)
+}
+
+// writeCommandsContext implements writeCommands, aborting when ctx is done
+func writeCommandsContext(ctx context.Context, output OutputMethod, data []byte, initDelay time.Duration) error {
+	if initDelay > 0 && len(data) >= 2 && data[0] == ESC && data[1] == '@' {
+		if err := writeOutput(ctx, output, data[:2]); err != nil {
+			return err
+		}
+		// The reset must reach the printer before the delay starts
+		if f, ok := output.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+		logger().Debug("Waiting for printer reset", "delay", initDelay)
+		select {
+		case <-time.After(initDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		data = data[2:]
+	}
+	return writeOutput(ctx, output, data)
+}
+
+// writeOutput writes data to an output, through WriteContext if it supports it
+func writeOutput(ctx context.Context, output OutputMethod, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if co, ok := output.(contextOutput); ok {
+		return co.WriteContext(ctx, data)
+	}
+	return output.Write(data)
+}
+
+// retryTransient runs op up to attempts times (at least once) while it fails with a
+// transient filesystem error, sleeping backoff between attempts
+func retryTransient(attempts int, backoff time.Duration, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= attempts || !isTransientFSError(err) {
+			return err
+		}
+		logger().Debug("Retrying after transient filesystem error", "attempt", attempt, "error", err)
+		time.Sleep(backoff)
+	}
+}
+
+// isTransientFSError reports whether err is a filesystem error that may succeed when retried
+func isTransientFSError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.EINTR, syscall.EIO, syscall.EBUSY, syscall.ESTALE, syscall.ETIMEDOUT} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressedOutput gzip-compresses data before passing it to another output method
+type CompressedOutput struct {
+	inner OutputMethod
+	gz    *gzip.Writer
+}
+
+// NewCompressedOutput creates a new gzip-compressing output method wrapping inner
+func NewCompressedOutput(inner OutputMethod) *CompressedOutput {
+	return &CompressedOutput{
+		inner: inner,
+		gz:    gzip.NewWriter(outputWriter{inner}),
+	}
+}
+
+// NewGzipFileOutput creates a new output method writing gzip-compressed data to a file
+func NewGzipFileOutput(filePath string) (*CompressedOutput, error) {
+	file, err := NewFileOutput(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewCompressedOutput(file), nil
+}
+
+// Write compresses data and writes it to the underlying output
+func (c *CompressedOutput) Write(data []byte) error {
+	_, err := c.gz.Write(data)
+	return err
+}
+
+// Close flushes and closes the gzip stream, then closes the underlying output
+func (c *CompressedOutput) Close() error {
+	if err := c.gz.Close(); err != nil {
+		return errors.Join(fmt.Errorf("failed to finish gzip stream: %w", err), c.inner.Close())
+	}
+	return c.inner.Close()
+}
+
+// BufferedOutput collects small writes and passes them to another output method in
+// larger blocks, reducing round-trips on high-latency links
+type BufferedOutput struct {
+	inner OutputMethod
+	buf   *bufio.Writer
+}
+
+// NewBufferedOutput creates a new buffering output method wrapping inner, which
+// receives writes of up to bufSize bytes (4096 if bufSize is not positive). Writes
+// of at least bufSize bytes bypass the buffer.
+func NewBufferedOutput(inner OutputMethod, bufSize int) *BufferedOutput {
+	if bufSize <= 0 {
+		bufSize = 4096
+	}
+	return &BufferedOutput{
+		inner: inner,
+		buf:   bufio.NewWriterSize(outputWriter{inner}, bufSize),
+	}
+}
+
+// NewBufferedOutputWithDelay creates a new buffering output method like
+// NewBufferedOutput that pauses for delay between the blocks passed to inner,
+// giving printers without flow control time to empty their receive buffer
+func NewBufferedOutputWithDelay(inner OutputMethod, bufSize int, delay time.Duration) *BufferedOutput {
+	if bufSize <= 0 {
+		bufSize = 4096
+	}
+	return &BufferedOutput{
+		inner: inner,
+		buf:   bufio.NewWriterSize(&pacedWriter{output: inner, delay: delay}, bufSize),
+	}
+}
+
+// Write buffers data, passing full blocks to the underlying output
+func (b *BufferedOutput) Write(data []byte) error {
+	_, err := b.buf.Write(data)
+	return err
+}
+
+// Flush passes all buffered data to the underlying output
+func (b *BufferedOutput) Flush() error {
+	return b.buf.Flush()
+}
+
+// Close flushes the buffered data, then closes the underlying output
+func (b *BufferedOutput) Close() error {
+	if err := b.buf.Flush(); err != nil {
+		b.inner.Close()
+		return fmt.Errorf("failed to flush buffered data: %w", err)
+	}
+	return b.inner.Close()
+}
+
+// MultiOutput passes data to several output methods, e.g. to keep a file copy of
+// every job sent to the printer
+type MultiOutput struct {
+	outputs []OutputMethod
+}
+
+// NewMultiOutput creates a new output method writing to all given outputs
+func NewMultiOutput(outputs ...OutputMethod) *MultiOutput {
+	return &MultiOutput{outputs: outputs}
+}
+
+// Write writes data to every output, even if writing to one of them fails, and
+// returns the errors of all failed outputs
+func (m *MultiOutput) Write(data []byte) error {
+	var errs []error
+	for i, output := range m.outputs {
+		if err := output.Write(data); err != nil {
+			errs = append(errs, fmt.Errorf("output %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every output and returns the errors of all that failed to close
+func (m *MultiOutput) Close() error {
+	var errs []error
+	for i, output := range m.outputs {
+		if err := output.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("output %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FailoverOutput writes to a primary output and switches to a backup output when
+// the primary fails. Unlike MultiOutput the job is printed only once: all data is
+// kept in memory, so the backup receives the complete job after a failure.
+type FailoverOutput struct {
+	primary OutputMethod
+	backup  OutputMethod
+
+	buf        bytes.Buffer
+	primaryErr error
+}
+
+// NewFailoverOutput creates a new output method writing to primary, or to backup
+// once writing to or closing primary fails
+func NewFailoverOutput(primary, backup OutputMethod) *FailoverOutput {
+	return &FailoverOutput{primary: primary, backup: backup}
+}
+
+// Write writes data to the primary output, failing over to the backup output
+func (f *FailoverOutput) Write(data []byte) error {
+	f.buf.Write(data)
+	if f.primaryErr != nil {
+		return f.backup.Write(data)
+	}
+	if err := f.primary.Write(data); err != nil {
+		return f.failover(err)
+	}
+	return nil
+}
+
+// Close closes both outputs. If closing the primary output fails, e.g. because
+// it sends the data on Close, the job is written to the backup output first.
+func (f *FailoverOutput) Close() error {
+	if f.primaryErr == nil {
+		if err := f.primary.Close(); err != nil {
+			if err := f.failover(err); err != nil {
+				return errors.Join(err, f.backup.Close())
+			}
+		}
+	} else if err := f.primary.Close(); err != nil {
+		logger().Debug("Failed to close failed primary output", "error", err)
+	}
+	if err := f.backup.Close(); err != nil {
+		return fmt.Errorf("backup output: %w", err)
+	}
+	return nil
+}
+
+// failover switches to the backup output after the primary failed with cause,
+// sending it all data written so far
+func (f *FailoverOutput) failover(cause error) error {
+	f.primaryErr = cause
+	logger().Warn("Primary output failed, switching to backup output", "error", cause, "bytes", f.buf.Len())
+	if err := f.backup.Write(f.buf.Bytes()); err != nil {
+		return fmt.Errorf("primary output: %w; backup output: %w", cause, err)
+	}
+	return nil
+}
+
+// outputWriter adapts an OutputMethod to io.Writer
+type outputWriter struct {
+	output OutputMethod
+}
+
+// Write implements io.Writer by forwarding to the wrapped output method
+func (w outputWriter) Write(p []byte) (int, error) {
+	if err := w.output.Write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// pacedWriter adapts an OutputMethod to io.Writer like outputWriter, pausing for
+// delay before every write but the first
+type pacedWriter struct {
+	output OutputMethod
+	delay  time.Duration
+	wrote  bool
+}
+
+// Write implements io.Writer by forwarding to the wrapped output method
+func (w *pacedWriter) Write(p []byte) (int, error) {
+	if w.wrote && w.delay > 0 {
+		time.Sleep(w.delay)
+	}
+	w.wrote = true
+	if err := w.output.Write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
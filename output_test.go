@@ -0,0 +1,349 @@
+package escposimg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// printerListener accepts connections on a reserved port once started and
+// collects the data of each connection
+type printerListener struct {
+	address  string
+	listener net.Listener
+	received chan []byte
+}
+
+// newPrinterListener reserves a local port without listening on it, so that
+// connections are refused until start is called
+func newPrinterListener(t *testing.T) *printerListener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+	return &printerListener{address: address, received: make(chan []byte, 8)}
+}
+
+func (p *printerListener) start(t *testing.T) {
+	l, err := net.Listen("tcp", p.address)
+	if err != nil {
+		t.Errorf("failed to listen on %s: %v", p.address, err)
+		return
+	}
+	p.listener = l
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				data, _ := io.ReadAll(conn)
+				conn.Close()
+				p.received <- data
+			}()
+		}
+	}()
+}
+
+func (p *printerListener) close() {
+	if p.listener != nil {
+		p.listener.Close()
+	}
+}
+
+// failingConn is a connection whose writes fail
+type failingConn struct {
+	net.Conn
+}
+
+func (c failingConn) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by printer")
+}
+
+// countDials replaces dialContext for the test, calling before(n) ahead of the
+// n-th dial; a non-nil conn returned by it is used instead of dialing
+func countDials(t *testing.T, before func(n int) net.Conn) *int {
+	dials := 0
+	dial := dialContext
+	dialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials++
+		if conn := before(dials); conn != nil {
+			return conn, nil
+		}
+		return dial(ctx, network, address)
+	}
+	t.Cleanup(func() { dialContext = dial })
+	return &dials
+}
+
+func TestNetworkOutputConnectRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		refused     int
+		wantErr     bool
+	}{
+		{"first attempt", 1, 0, false},
+		{"succeeds after retries", 3, 2, false},
+		{"gives up", 2, 2, true},
+		{"single attempt", 0, 1, true},
+	}
+	for _, tt := range tests {
+		printer := newPrinterListener(t)
+		dials := countDials(t, func(n int) net.Conn {
+			// The printer comes up after refusing the first connections
+			if n == tt.refused+1 {
+				printer.start(t)
+			}
+			return nil
+		})
+
+		output, err := NewNetworkOutputWithConfig(printer.address, NetworkOutputConfig{MaxAttempts: tt.maxAttempts})
+		wantDials := min(tt.refused+1, max(tt.maxAttempts, 1))
+		if *dials != wantDials {
+			t.Errorf("%s: %d connection attempts, want %d", tt.name, *dials, wantDials)
+		}
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: NewNetworkOutputWithConfig() returned no error", tt.name)
+				output.Close()
+			}
+			printer.close()
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: NewNetworkOutputWithConfig() error = %v", tt.name, err)
+		}
+		if err := output.Write([]byte("receipt")); err != nil {
+			t.Errorf("%s: Write() error = %v", tt.name, err)
+		}
+		output.Close()
+		if got := <-printer.received; string(got) != "receipt" {
+			t.Errorf("%s: printer received %q, want %q", tt.name, got, "receipt")
+		}
+		printer.close()
+	}
+}
+
+func TestNetworkOutputWriteRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		failures    int
+		wantErr     bool
+	}{
+		{"resends after reconnect", 2, 1, false},
+		{"gives up", 2, 2, true},
+	}
+	for _, tt := range tests {
+		printer := newPrinterListener(t)
+		printer.start(t)
+		dials := countDials(t, func(n int) net.Conn {
+			if n <= tt.failures {
+				client, server := net.Pipe()
+				server.Close()
+				return failingConn{client}
+			}
+			return nil
+		})
+
+		output, err := NewNetworkOutputWithConfig(printer.address, NetworkOutputConfig{MaxAttempts: tt.maxAttempts})
+		if err != nil {
+			t.Fatalf("%s: NewNetworkOutputWithConfig() error = %v", tt.name, err)
+		}
+		err = output.Write([]byte("receipt"))
+		output.Close()
+		if *dials != tt.maxAttempts {
+			t.Errorf("%s: %d connections, want %d", tt.name, *dials, tt.maxAttempts)
+		}
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Write() returned no error", tt.name)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%s: Write() error = %v", tt.name, err)
+			}
+			if got := <-printer.received; string(got) != "receipt" {
+				t.Errorf("%s: printer received %q, want %q", tt.name, got, "receipt")
+			}
+		}
+		printer.close()
+	}
+}
+
+func TestBufferedOutputReducesWrites(t *testing.T) {
+	chunk := bytes.Repeat([]byte{0x5A}, 10)
+	tests := []struct {
+		name    string
+		bufSize int
+		writes  int
+	}{
+		{"unbuffered", 0, 100},
+		{"256 bytes", 256, 4},
+		{"default size", -1, 1},
+	}
+	for _, tt := range tests {
+		var inner memoryOutput
+		var output OutputMethod = &inner
+		if tt.bufSize != 0 {
+			output = NewBufferedOutput(&inner, tt.bufSize)
+		}
+		for i := 0; i < 100; i++ {
+			if err := output.Write(chunk); err != nil {
+				t.Fatalf("%s: Write() error = %v", tt.name, err)
+			}
+		}
+		if err := output.Close(); err != nil {
+			t.Fatalf("%s: Close() error = %v", tt.name, err)
+		}
+		if inner.writes != tt.writes {
+			t.Errorf("%s: %d underlying writes, want %d", tt.name, inner.writes, tt.writes)
+		}
+		if inner.Len() != 1000 || !inner.closed {
+			t.Errorf("%s: underlying output got %d bytes (closed: %v), want 1000", tt.name, inner.Len(), inner.closed)
+		}
+	}
+
+	// Writes of at least the buffer size are passed through
+	var inner memoryOutput
+	output := NewBufferedOutput(&inner, 16)
+	output.Write(bytes.Repeat([]byte{1}, 64))
+	if inner.writes != 1 || inner.Len() != 64 {
+		t.Errorf("large write: %d underlying writes of %d bytes, want one of 64", inner.writes, inner.Len())
+	}
+}
+
+func TestBufferedOutputDelay(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	var inner memoryOutput
+	output := NewBufferedOutputWithDelay(&inner, 16, delay)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		output.Write(bytes.Repeat([]byte{byte(i)}, 16))
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if inner.writes != 4 || inner.Len() != 64 {
+		t.Errorf("%d underlying writes of %d bytes, want 4 of 64", inner.writes, inner.Len())
+	}
+	// The first block is passed on at once, the other three after a delay
+	if elapsed := time.Since(start); elapsed < 3*delay {
+		t.Errorf("4 blocks took %s, want at least %s", elapsed, 3*delay)
+	}
+}
+
+// errorOutput fails every write and close, closing with closeErr when it is set
+type errorOutput struct {
+	err      error
+	closeErr error
+}
+
+func (e errorOutput) Write([]byte) error { return e.err }
+
+func (e errorOutput) Close() error {
+	if e.closeErr != nil {
+		return e.closeErr
+	}
+	return e.err
+}
+
+func TestMultiOutput(t *testing.T) {
+	var first, second memoryOutput
+	output := NewMultiOutput(&first, &second)
+	job := []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 1, 0, 0x81, LF}
+	output.Write(job[:5])
+	output.Write(job[5:])
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for i, out := range []*memoryOutput{&first, &second} {
+		if !bytes.Equal(out.Bytes(), job) || !out.closed {
+			t.Errorf("output %d got % X (closed: %v), want % X", i+1, out.Bytes(), out.closed, job)
+		}
+	}
+
+	// Failing outputs do not stop the others, and all errors are returned
+	errA, errB := errors.New("printer offline"), errors.New("disk full")
+	var ok memoryOutput
+	output = NewMultiOutput(errorOutput{err: errA}, &ok, errorOutput{err: errB})
+	err := output.Write(job)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Write() error = %v, want both output errors", err)
+	}
+	if err != nil && (!strings.Contains(err.Error(), "output 1") || !strings.Contains(err.Error(), "output 3")) {
+		t.Errorf("Write() error = %v, want the failed outputs numbered", err)
+	}
+	if !bytes.Equal(ok.Bytes(), job) {
+		t.Errorf("working output got % X, want % X", ok.Bytes(), job)
+	}
+	if err := output.Close(); !errors.Is(err, errA) || !errors.Is(err, errB) || !ok.closed {
+		t.Errorf("Close() error = %v (working output closed: %v), want both errors", err, ok.closed)
+	}
+}
+
+func TestCompressedOutputCloseErrors(t *testing.T) {
+	errWrite := errors.New("write failed")
+	errClose := errors.New("close failed")
+	output := NewCompressedOutput(errorOutput{err: errWrite, closeErr: errClose})
+	output.Write([]byte{ESC, '@'})
+	err := output.Close()
+	if !errors.Is(err, errWrite) || !errors.Is(err, errClose) {
+		t.Errorf("Close() error = %v, want the gzip and the inner close error", err)
+	}
+}
+
+func TestDeviceOutput(t *testing.T) {
+	job := []byte{ESC, '@', GS, 'v', '0', 0, 1, 0, 1, 0, 0x81, LF}
+
+	// A regular file stands in for the device; it is appended to, not truncated
+	path := filepath.Join(t.TempDir(), "lp0")
+	if err := os.WriteFile(path, []byte{ESC, '@'}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := NewDeviceOutput(path)
+	if err != nil {
+		t.Fatalf("NewDeviceOutput() error = %v", err)
+	}
+	if err := output.Write(job[:5]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := output.Write(job[5:]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{ESC, '@'}, job...); !bytes.Equal(got, want) {
+		t.Errorf("device got % X, want % X", got, want)
+	}
+	if err := output.Write(job); err == nil {
+		t.Errorf("Write() after Close() succeeded, want an error")
+	}
+
+	// Unlike a file output, a missing device is not created
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewDeviceOutput(missing); err == nil {
+		t.Errorf("NewDeviceOutput() of a missing device succeeded")
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Errorf("NewDeviceOutput() created %s", missing)
+	}
+}
@@ -0,0 +1,427 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Command is a single command decoded from an ESC/POS byte stream
+type Command struct {
+	// Byte offset of the command within the stream
+	Offset int
+
+	// Total length of the command in bytes, including parameters and data
+	Length int
+
+	// Mnemonic of the command (e.g. "ESC @", "GS v 0"), "TEXT" for printable
+	// characters or "UNKNOWN" for bytes the parser does not recognise
+	Name string
+
+	// Fixed parameter bytes following the command prefix
+	Params []byte
+
+	// Variable-length payload (image data, barcode data or text)
+	Data []byte
+}
+
+// String returns a human-readable description of the command
+func (c Command) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%08x  %-8s", c.Offset, c.Name)
+	if len(c.Params) > 0 {
+		fmt.Fprintf(&sb, " params=% x", c.Params)
+	}
+	if c.Name == "TEXT" {
+		fmt.Fprintf(&sb, " %q", c.Data)
+	} else if len(c.Data) > 0 {
+		fmt.Fprintf(&sb, " data=%d bytes", len(c.Data))
+	}
+	if detail := c.detail(); detail != "" {
+		sb.WriteString(" (")
+		sb.WriteString(detail)
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// detail returns a decoded summary for commands with well-known parameters
+func (c Command) detail() string {
+	switch c.Name {
+	case "GS v 0":
+		return fmt.Sprintf("raster %dx%d dots, m=%d",
+			int(le16(c.Params[1:3]))*8, le16(c.Params[3:5]), c.Params[0])
+	case "ESC *":
+		return fmt.Sprintf("bit image m=%d, %d dots wide", c.Params[0], le16(c.Params[1:3]))
+	case "GS V":
+		switch c.Params[0] {
+		case 0, 48, 65, 97:
+			return "full paper cut"
+		}
+		return "partial paper cut"
+	case "GS b":
+		if c.Params[0]&1 != 0 {
+			return "smoothing on"
+		}
+		return "smoothing off"
+	case "ESC @":
+		return "initialize printer"
+	case "GS B":
+		if c.Params[0]&1 != 0 {
+			return "reverse printing on"
+		}
+		return "reverse printing off"
+	case "GS k":
+		return fmt.Sprintf("barcode m=%d %q", c.Params[0], c.Data)
+	case "ESC J":
+		return fmt.Sprintf("feed %d dots", c.Params[0])
+	case "ESC a":
+		return fmt.Sprintf("justification %s", Alignment(c.Params[0]%48))
+	case "ESC 2":
+		return "default line spacing"
+	case "ESC 3":
+		return fmt.Sprintf("line spacing %d dots", c.Params[0])
+	case "ESC GS S":
+		return fmt.Sprintf("star raster %dx%d dots", int(le16(c.Params[1:3]))*8, le16(c.Params[3:5]))
+	case "GS *":
+		return fmt.Sprintf("define downloaded bit image %dx%d dots", int(c.Params[0])*8, int(c.Params[1])*8)
+	case "GS /":
+		return fmt.Sprintf("print downloaded bit image, m=%d", c.Params[0])
+	case "GS P":
+		return fmt.Sprintf("motion units x=1/%d y=1/%d inch", c.Params[0], c.Params[1])
+	case "GS ( L", "GS 8 L":
+		return graphicsDetail(c.Params)
+	case "GS ( k":
+		return qrCodeDetail(c.Params)
+	}
+	return ""
+}
+
+// escParams lists the number of fixed parameter bytes for ESC commands
+var escParams = map[byte]int{
+	'@': 0,
+	'!': 1,
+	'-': 1,
+	'2': 0,
+	'3': 1,
+	'E': 1,
+	'J': 1,
+	'a': 1,
+	'd': 1,
+	't': 1,
+}
+
+// gsParams lists the number of fixed parameter bytes for GS commands
+var gsParams = map[byte]int{
+	'!': 1,
+	'/': 1,
+	'B': 1,
+	'H': 1,
+	'P': 2,
+	'b': 1,
+	'h': 1,
+	'w': 1,
+}
+
+// ParseESCPOS decodes an ESC/POS byte stream into its individual commands.
+//
+// Only the commands this package emits (plus a few common formatting commands)
+// are recognised; any other control byte is reported as an "UNKNOWN" command of
+// length one so that the rest of the stream can still be inspected. An error is
+// returned if a recognised command is truncated.
+func ParseESCPOS(data []byte) ([]Command, error) {
+	var commands []Command
+
+	pos := 0
+	for pos < len(data) {
+		cmd, err := parseCommand(data, pos)
+		if err != nil {
+			return commands, err
+		}
+		commands = append(commands, cmd)
+		pos += cmd.Length
+	}
+
+	return commands, nil
+}
+
+// ImageDataOffset locates the pixel data of the first image command in an ESC/POS
+// byte stream, returning its offset and length in data. Raster (GS v 0), bit image
+// (ESC *), StarPRNT raster (ESC GS S), downloaded bit image (GS *) and stored
+// graphics (GS ( L / GS 8 L) commands are recognised.
+//
+// This allows splicing or re-sending just the pixel data of generated commands,
+// e.g. after a partial write. For bit images only the first band is returned.
+func ImageDataOffset(data []byte) (offset, length int, err error) {
+	commands, err := ParseESCPOS(data)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse commands: %w", err)
+	}
+	for _, cmd := range commands {
+		switch cmd.Name {
+		case "GS v 0", "ESC *", "ESC GS S", "GS *", "GS ( L", "GS 8 L":
+			if len(cmd.Data) == 0 {
+				continue
+			}
+			// The pixel data always ends the command
+			return cmd.Offset + cmd.Length - len(cmd.Data), len(cmd.Data), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("no image data found")
+}
+
+// parseCommand decodes the command starting at pos
+func parseCommand(data []byte, pos int) (Command, error) {
+	b := data[pos]
+
+	switch {
+	case b == LF:
+		return Command{Offset: pos, Length: 1, Name: "LF"}, nil
+	case b == CR:
+		return Command{Offset: pos, Length: 1, Name: "CR"}, nil
+	case b == ESC:
+		return parseESCCommand(data, pos)
+	case b == GS:
+		return parseGSCommand(data, pos)
+	case b >= 0x20:
+		end := pos
+		for end < len(data) && data[end] >= 0x20 {
+			end++
+		}
+		return Command{Offset: pos, Length: end - pos, Name: "TEXT", Data: data[pos:end]}, nil
+	default:
+		return Command{Offset: pos, Length: 1, Name: "UNKNOWN", Params: data[pos : pos+1]}, nil
+	}
+}
+
+// parseESCCommand decodes a command starting with ESC
+func parseESCCommand(data []byte, pos int) (Command, error) {
+	if pos+1 >= len(data) {
+		return Command{}, truncatedError("ESC", pos)
+	}
+	code := data[pos+1]
+	name := "ESC " + string(code)
+
+	switch code {
+	case '*':
+		// ESC * m nL nH [data]
+		params, err := readBytes(data, pos+2, 3, name)
+		if err != nil {
+			return Command{}, err
+		}
+		dataLen := int(le16(params[1:3]))
+		if params[0] == 32 || params[0] == 33 {
+			dataLen *= 3
+		}
+		payload, err := readBytes(data, pos+5, dataLen, name)
+		if err != nil {
+			return Command{}, err
+		}
+		return Command{Offset: pos, Length: 5 + dataLen, Name: name, Params: params, Data: payload}, nil
+	case GS:
+		// ESC GS S m xL xH yL yH n [data] (StarPRNT raster image)
+		if pos+2 >= len(data) || data[pos+2] != 'S' {
+			break
+		}
+		name = "ESC GS S"
+		params, err := readBytes(data, pos+3, 6, name)
+		if err != nil {
+			return Command{}, err
+		}
+		dataLen := int(le16(params[1:3])) * int(le16(params[3:5]))
+		payload, err := readBytes(data, pos+9, dataLen, name)
+		if err != nil {
+			return Command{}, err
+		}
+		return Command{Offset: pos, Length: 9 + dataLen, Name: name, Params: params, Data: payload}, nil
+	}
+
+	n, ok := escParams[code]
+	if !ok {
+		return Command{Offset: pos, Length: 1, Name: "UNKNOWN", Params: data[pos : pos+1]}, nil
+	}
+	params, err := readBytes(data, pos+2, n, name)
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{Offset: pos, Length: 2 + n, Name: name, Params: params}, nil
+}
+
+// parseGSCommand decodes a command starting with GS
+func parseGSCommand(data []byte, pos int) (Command, error) {
+	if pos+1 >= len(data) {
+		return Command{}, truncatedError("GS", pos)
+	}
+	code := data[pos+1]
+	name := "GS " + string(code)
+
+	switch code {
+	case 'v':
+		// GS v 0 m xL xH yL yH [data]
+		params, err := readBytes(data, pos+2, 6, name)
+		if err != nil {
+			return Command{}, err
+		}
+		if params[0] != '0' {
+			return Command{Offset: pos, Length: 1, Name: "UNKNOWN", Params: data[pos : pos+1]}, nil
+		}
+		name = "GS v 0"
+		params = params[1:]
+		dataLen := int(le16(params[1:3])) * int(le16(params[3:5]))
+		payload, err := readBytes(data, pos+8, dataLen, name)
+		if err != nil {
+			return Command{}, err
+		}
+		return Command{Offset: pos, Length: 8 + dataLen, Name: name, Params: params, Data: payload}, nil
+	case 'V':
+		// GS V m, or GS V m n for the feed-and-cut variants
+		params, err := readBytes(data, pos+2, 1, name)
+		if err != nil {
+			return Command{}, err
+		}
+		if params[0] == 65 || params[0] == 66 || params[0] == 97 || params[0] == 98 {
+			params, err = readBytes(data, pos+2, 2, name)
+			if err != nil {
+				return Command{}, err
+			}
+		}
+		return Command{Offset: pos, Length: 2 + len(params), Name: name, Params: params}, nil
+	case 'k':
+		// GS k m d1...dk NUL (m 0-6), or GS k m n d1...dn (m 65-73)
+		params, err := readBytes(data, pos+2, 1, name)
+		if err != nil {
+			return Command{}, err
+		}
+		if params[0] >= 65 {
+			params, err = readBytes(data, pos+2, 2, name)
+			if err != nil {
+				return Command{}, err
+			}
+			payload, err := readBytes(data, pos+4, int(params[1]), name)
+			if err != nil {
+				return Command{}, err
+			}
+			return Command{Offset: pos, Length: 4 + len(payload), Name: name, Params: params, Data: payload}, nil
+		}
+		end := bytes.IndexByte(data[pos+3:], 0)
+		if end < 0 {
+			return Command{}, truncatedError(name, pos)
+		}
+		return Command{Offset: pos, Length: 4 + end, Name: name, Params: params, Data: data[pos+3 : pos+3+end]}, nil
+	case '*':
+		// GS * x y [data], x and y in blocks of 8 dots
+		params, err := readBytes(data, pos+2, 2, name)
+		if err != nil {
+			return Command{}, err
+		}
+		payload, err := readBytes(data, pos+4, int(params[0])*int(params[1])*8, name)
+		if err != nil {
+			return Command{}, err
+		}
+		return Command{Offset: pos, Length: 4 + len(payload), Name: name, Params: params, Data: payload}, nil
+	case '(':
+		// GS ( fn pL pH [m fn ...], with pL/pH giving the number of bytes that follow
+		header, err := readBytes(data, pos+2, 3, "GS (")
+		if err != nil {
+			return Command{}, err
+		}
+		name = "GS ( " + string(header[0])
+		payload, err := readBytes(data, pos+5, int(le16(header[1:3])), name)
+		if err != nil {
+			return Command{}, err
+		}
+		params, graphics := splitGraphicsPayload(payload)
+		return Command{Offset: pos, Length: 5 + len(payload), Name: name, Params: params, Data: graphics}, nil
+	case '8':
+		// GS 8 fn p1 p2 p3 p4 [m fn ...], the GS ( variant with a 32-bit length
+		header, err := readBytes(data, pos+2, 5, "GS 8")
+		if err != nil {
+			return Command{}, err
+		}
+		name = "GS 8 " + string(header[0])
+		size := int(le16(header[1:3])) | int(le16(header[3:5]))<<16
+		payload, err := readBytes(data, pos+7, size, name)
+		if err != nil {
+			return Command{}, err
+		}
+		params, graphics := splitGraphicsPayload(payload)
+		return Command{Offset: pos, Length: 7 + len(payload), Name: name, Params: params, Data: graphics}, nil
+	}
+
+	n, ok := gsParams[code]
+	if !ok {
+		return Command{Offset: pos, Length: 1, Name: "UNKNOWN", Params: data[pos : pos+1]}, nil
+	}
+	params, err := readBytes(data, pos+2, n, name)
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{Offset: pos, Length: 2 + n, Name: name, Params: params}, nil
+}
+
+// splitGraphicsPayload separates the header of a raster graphics store command
+// (fn 112) from its image data; other functions are returned as parameters only
+func splitGraphicsPayload(payload []byte) (params, data []byte) {
+	if len(payload) >= 10 && payload[1] == 112 {
+		return payload[:10], payload[10:]
+	}
+	return payload, nil
+}
+
+// graphicsDetail describes a GS ( L / GS 8 L graphics command from its m fn ... bytes
+func graphicsDetail(p []byte) string {
+	if len(p) < 2 {
+		return ""
+	}
+	switch p[1] {
+	case 50:
+		return "print buffered graphics"
+	case 69:
+		if len(p) >= 4 {
+			return fmt.Sprintf("print stored graphics key=%q", p[2:4])
+		}
+	case 112:
+		if len(p) >= 10 {
+			return fmt.Sprintf("store raster graphics %dx%d dots, color=%d", le16(p[6:8]), le16(p[8:10]), p[5])
+		}
+	}
+	return ""
+}
+
+// qrCodeDetail describes a GS ( k QR code command from its cn fn ... bytes
+func qrCodeDetail(p []byte) string {
+	if len(p) < 3 || p[0] != 49 {
+		return ""
+	}
+	switch p[1] {
+	case 65:
+		return fmt.Sprintf("QR code model %d", p[2]-48)
+	case 67:
+		return fmt.Sprintf("QR code module size %d dots", p[2])
+	case 69:
+		return fmt.Sprintf("QR code error correction %c", "LMQH"[p[2]%4])
+	case 80:
+		return fmt.Sprintf("store QR code data %q", p[3:])
+	case 81:
+		return "print QR code"
+	}
+	return ""
+}
+
+// readBytes returns n bytes starting at start, or an error if the stream is too short
+func readBytes(data []byte, start, n int, name string) ([]byte, error) {
+	if start+n > len(data) {
+		return nil, truncatedError(name, start)
+	}
+	return data[start : start+n], nil
+}
+
+// truncatedError reports a command that runs past the end of the stream
+func truncatedError(name string, offset int) error {
+	return fmt.Errorf("truncated %s command at offset %d", name, offset)
+}
+
+// le16 decodes a little-endian 16-bit value
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
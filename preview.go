@@ -0,0 +1,458 @@
+package escposimg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// Dimensions used when rendering a receipt preview
+const (
+	previewCharWidth  = 12 // Width of a Font A character in dots
+	previewCharHeight = 24 // Height of a Font A character in dots
+	previewDashLength = 8  // Length of the dashes marking the cut line
+	previewRedLevel   = 96 // Gray level used for the red plane of two-color graphics
+
+	previewBarcodeHeight = 162 // Default barcode height in dots (GS h)
+	previewBarcodeModule = 3   // Default barcode module width in dots (GS w)
+	previewBarcodeChar   = 11  // Approximate number of modules per barcode character
+	previewQRModules     = 29  // Modules per side of a QR code placeholder (version 3)
+	previewQRModule      = 3   // Default QR code module size in dots
+)
+
+// ReceiptPreview renders the complete receipt that would be printed for an image,
+// including debug text, paper feeds and a dashed line marking the cut position.
+//
+// The preview is produced by running the normal pipeline and rendering the resulting
+// ESC/POS stream at the printer's dot scale, so it reflects exactly what is sent.
+func ReceiptPreview(imagePath string, config *Config) (image.Image, error) {
+	data, err := GenerateImageCommands(imagePath, config)
+	if err != nil {
+		return nil, err
+	}
+	return RenderESCPOS(data, config)
+}
+
+// RenderESCPOS renders an ESC/POS stream into an image at the printer's dot scale.
+//
+// Image commands are drawn pixel-exact and justified as set with ESC a, with the
+// red plane of two-color graphics in gray. Line feeds advance the paper by the
+// default line spacing (1/6 inch), text, barcodes and QR codes are drawn as gray
+// or striped placeholders and paper cuts are shown as a dashed line across the
+// receipt. ESC d is read as a cut for DialectStar and as a feed otherwise.
+//
+// Graphics printed from the printer's NV memory (GS ( L fn 69) cannot be
+// previewed, as the stream does not contain them; they are reported as error.
+func RenderESCPOS(data []byte, config *Config) (image.Image, error) {
+	commands, err := ParseESCPOS(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ESC/POS data: %w", err)
+	}
+
+	r := &receiptRenderer{
+		canvas:     image.NewGray(image.Rect(0, 0, config.CalculatePixelWidth(), 0)),
+		lineHeight: config.VerticalDPI() / 6,
+
+		barcodeHeight: previewBarcodeHeight,
+		barcodeModule: previewBarcodeModule,
+		qrModule:      previewQRModule,
+		dialect:       config.Dialect,
+	}
+	for _, cmd := range commands {
+		if err := r.render(cmd); err != nil {
+			return nil, fmt.Errorf("failed to render command at offset %d: %w", cmd.Offset, err)
+		}
+	}
+
+	logger().Debug("Rendered receipt preview",
+		"width", r.canvas.Bounds().Dx(),
+		"height", r.canvas.Bounds().Dy(),
+		"commands", len(commands))
+
+	return r.canvas, nil
+}
+
+// receiptRenderer draws ESC/POS commands onto a growing canvas
+type receiptRenderer struct {
+	canvas     *image.Gray
+	lineHeight int
+
+	// Current print position
+	x, y int
+
+	// Height of the tallest element printed on the current line
+	lineContent int
+
+	// Graphics planes stored in the print buffer, waiting to be printed
+	buffered []Command
+
+	// Text is printed white on black (GS B)
+	reverse bool
+
+	// Barcode settings (GS h, GS w, GS H)
+	barcodeHeight, barcodeModule int
+	barcodeHRI                   bool
+
+	// QR code module size (GS ( k fn 67)
+	qrModule int
+
+	// Justification (ESC a): 0 left, 1 center, 2 right
+	justification byte
+
+	// Downloaded bit image (GS *), printed with GS /
+	downloaded *Command
+
+	// Command set, deciding the meaning of ESC d
+	dialect Dialect
+}
+
+// render draws a single command
+func (r *receiptRenderer) render(cmd Command) error {
+	switch cmd.Name {
+	case "GS v 0":
+		r.drawRaster(cmd)
+	case "ESC GS S":
+		r.drawStarRaster(cmd)
+	case "ESC *":
+		r.drawBitImage(cmd)
+	case "GS *":
+		r.downloaded = &cmd
+	case "GS /":
+		return r.drawDownloaded(cmd)
+	case "TEXT":
+		r.drawText(cmd)
+	case "LF":
+		r.newLine()
+	case "GS V":
+		r.drawCutLine()
+	case "ESC d":
+		if r.dialect == DialectStar {
+			r.drawCutLine()
+		} else {
+			r.feed(int(cmd.Params[0]) * r.lineHeight)
+		}
+	case "ESC a":
+		r.justification = cmd.Params[0] % 48
+	case "GS ( L", "GS 8 L":
+		return r.handleGraphics(cmd)
+	case "GS ( k":
+		r.handleQRCode(cmd)
+	case "ESC J":
+		r.feed(int(cmd.Params[0]))
+	case "GS k":
+		r.drawBarcode(cmd)
+	case "GS h":
+		r.barcodeHeight = int(cmd.Params[0])
+	case "GS w":
+		r.barcodeModule = int(cmd.Params[0])
+	case "GS H":
+		r.barcodeHRI = cmd.Params[0]&2 != 0
+	case "GS B":
+		r.reverse = cmd.Params[0]&1 != 0
+	}
+	return nil
+}
+
+// justifiedX returns the left edge of an image of the given width on the paper
+// according to the justification
+func (r *receiptRenderer) justifiedX(width int) int {
+	free := max(r.canvas.Bounds().Dx()-width, 0)
+	switch r.justification {
+	case 1:
+		return free / 2
+	case 2:
+		return free
+	default:
+		return 0
+	}
+}
+
+// handleQRCode stores the QR code module size and draws a placeholder for the
+// print function (fn 81)
+func (r *receiptRenderer) handleQRCode(cmd Command) {
+	if len(cmd.Params) < 3 || cmd.Params[0] != 49 {
+		return
+	}
+	switch cmd.Params[1] {
+	case 67:
+		r.qrModule = max(int(cmd.Params[2]), 1)
+	case 81:
+		size := previewQRModules * r.qrModule
+		x := r.justifiedX(size)
+		r.ensureSize(x+size, r.y+size)
+		for row := 0; row < size; row += 2 * r.qrModule {
+			r.fill(x, r.y+row, size, r.qrModule, 0)
+		}
+		r.y += size
+		r.x = 0
+	}
+}
+
+// feed advances the paper by the given number of dots
+func (r *receiptRenderer) feed(dots int) {
+	r.ensureHeight(r.y + dots)
+	r.y += dots
+	r.x = 0
+	r.lineContent = 0
+}
+
+// drawBarcode draws a striped placeholder of the approximate barcode size,
+// followed by a text placeholder when human readable characters are enabled
+func (r *receiptRenderer) drawBarcode(cmd Command) {
+	module := max(r.barcodeModule, 1)
+	width := min(len(cmd.Data)*previewBarcodeChar*module, r.canvas.Bounds().Dx())
+	r.ensureHeight(r.y + r.barcodeHeight)
+	for x := 0; x < width; x += 2 * module {
+		r.fill(x, r.y, module, r.barcodeHeight, 0)
+	}
+	r.y += r.barcodeHeight
+	r.x = 0
+
+	if r.barcodeHRI {
+		r.drawText(Command{Data: cmd.Data})
+		r.feed(previewCharHeight)
+	}
+}
+
+// handleGraphics buffers stored raster graphics (fn 112) and draws them when
+// the print command (fn 50) arrives. The red plane is drawn in gray.
+func (r *receiptRenderer) handleGraphics(cmd Command) error {
+	if len(cmd.Params) < 2 {
+		return nil
+	}
+	switch cmd.Params[1] {
+	case 69:
+		return fmt.Errorf("cannot preview NV graphics printed by key, the image is stored in the printer")
+	case 112:
+		if len(cmd.Params) >= 10 {
+			r.buffered = append(r.buffered, cmd)
+		}
+	case 50:
+		height := 0
+		for _, plane := range r.buffered {
+			p := plane.Params
+			width, planeHeight := int(le16(p[6:8])), int(le16(p[8:10]))
+			level := uint8(0)
+			if p[5] == 50 || p[5] == 51 {
+				level = previewRedLevel
+			}
+			bytesPerLine := (width + 7) / 8
+			if len(plane.Data) < bytesPerLine*planeHeight {
+				continue
+			}
+			x := r.justifiedX(width)
+			r.ensureSize(x+width, r.y+planeHeight)
+			for row := 0; row < planeHeight; row++ {
+				for col := 0; col < width; col++ {
+					if plane.Data[row*bytesPerLine+col/8]&(1<<uint(7-col%8)) != 0 {
+						r.fill(x+col, r.y+row, 1, 1, level)
+					}
+				}
+			}
+			height = max(height, planeHeight)
+		}
+		r.y += height
+		r.buffered = nil
+	}
+	return nil
+}
+
+// newLine advances the paper by one line
+func (r *receiptRenderer) newLine() {
+	advance := r.lineHeight
+	if r.lineContent > 0 {
+		// Bit image bands are printed with line spacing matching the band height
+		advance = r.lineContent
+	}
+	r.ensureHeight(r.y + advance)
+	r.y += advance
+	r.x = 0
+	r.lineContent = 0
+}
+
+// drawRaster draws a GS v 0 raster image and moves below it
+func (r *receiptRenderer) drawRaster(cmd Command) {
+	bytesPerLine := int(le16(cmd.Params[1:3]))
+	height := int(le16(cmd.Params[3:5]))
+	scaleX, scaleY := rasterScaleFactors(cmd.Params[0])
+	r.drawRows(cmd.Data, bytesPerLine, height, scaleX, scaleY)
+}
+
+// drawStarRaster draws a StarPRNT ESC GS S raster image and moves below it
+func (r *receiptRenderer) drawStarRaster(cmd Command) {
+	bytesPerLine := int(le16(cmd.Params[1:3]))
+	height := int(le16(cmd.Params[3:5]))
+	r.drawRows(cmd.Data, bytesPerLine, height, 1, 1)
+}
+
+// rasterScaleFactors returns the enlargement of the GS v 0 and GS / m parameter:
+// m=1/49 doubles the width, m=2/50 the height, m=3/51 both
+func rasterScaleFactors(m byte) (scaleX, scaleY int) {
+	scaleX, scaleY = 1, 1
+	if m&1 != 0 {
+		scaleX = 2
+	}
+	if m&2 != 0 {
+		scaleY = 2
+	}
+	return scaleX, scaleY
+}
+
+// drawRows draws row-major raster data with the most significant bit as the
+// leftmost dot, justified on the paper, and moves below it
+func (r *receiptRenderer) drawRows(data []byte, bytesPerLine, height, scaleX, scaleY int) {
+	x := r.justifiedX(bytesPerLine * 8 * scaleX)
+	r.ensureSize(x+bytesPerLine*8*scaleX, r.y+height*scaleY)
+	for row := 0; row < height; row++ {
+		for col := 0; col < bytesPerLine*8; col++ {
+			if data[row*bytesPerLine+col/8]&(1<<uint(7-col%8)) != 0 {
+				r.fill(x+col*scaleX, r.y+row*scaleY, scaleX, scaleY, 0)
+			}
+		}
+	}
+	r.y += height * scaleY
+}
+
+// drawDownloaded draws the bit image defined with GS *, stored column by column
+// with the most significant bit as the top dot, and moves below it
+func (r *receiptRenderer) drawDownloaded(cmd Command) error {
+	if r.downloaded == nil {
+		return fmt.Errorf("GS / prints a downloaded bit image, but none was defined with GS *")
+	}
+	blocksX, blocksY := int(r.downloaded.Params[0]), int(r.downloaded.Params[1])
+	width, height := blocksX*8, blocksY*8
+	scaleX, scaleY := rasterScaleFactors(cmd.Params[0])
+
+	x := r.justifiedX(width * scaleX)
+	r.ensureSize(x+width*scaleX, r.y+height*scaleY)
+	for col := 0; col < width; col++ {
+		for row := 0; row < height; row++ {
+			if r.downloaded.Data[col*blocksY+row/8]&(0x80>>uint(row%8)) != 0 {
+				r.fill(x+col*scaleX, r.y+row*scaleY, scaleX, scaleY, 0)
+			}
+		}
+	}
+	r.y += height * scaleY
+	return nil
+}
+
+// drawBitImage draws a single ESC * band at the current line
+func (r *receiptRenderer) drawBitImage(cmd Command) {
+	width := int(le16(cmd.Params[1:3]))
+	dotsPerColumn := 8
+	if cmd.Params[0] == 32 || cmd.Params[0] == 33 {
+		dotsPerColumn = 24
+	}
+	bytesPerColumn := dotsPerColumn / 8
+
+	// Justification applies to the start of the line
+	if r.x == 0 {
+		r.x = r.justifiedX(width)
+	}
+	r.ensureSize(r.x+width, r.y+dotsPerColumn)
+	for col := 0; col < width; col++ {
+		for bit := 0; bit < dotsPerColumn; bit++ {
+			b := cmd.Data[col*bytesPerColumn+bit/8]
+			// 8-dot bands are packed LSB first, 24-dot bands MSB first
+			mask := byte(1) << uint(bit%8)
+			if dotsPerColumn == 24 {
+				mask = byte(0x80) >> uint(bit%8)
+			}
+			if b&mask != 0 {
+				r.fill(r.x+col, r.y+bit, 1, 1, 0)
+			}
+		}
+	}
+	r.x += width
+	r.lineContent = max(r.lineContent, dotsPerColumn)
+}
+
+// drawText draws gray placeholder blocks for each character, on black when reversed
+func (r *receiptRenderer) drawText(cmd Command) {
+	r.ensureHeight(r.y + previewCharHeight)
+	width := r.canvas.Bounds().Dx()
+	for range cmd.Data {
+		if r.x+previewCharWidth > width {
+			r.newLine()
+			r.ensureHeight(r.y + previewCharHeight)
+		}
+		if r.reverse {
+			r.fill(r.x, r.y, previewCharWidth, previewCharHeight, 0)
+		}
+		r.fill(r.x+1, r.y+2, previewCharWidth-2, previewCharHeight-4, 160)
+		r.x += previewCharWidth
+	}
+}
+
+// drawCutLine marks the cut position with a dashed line
+func (r *receiptRenderer) drawCutLine() {
+	r.ensureHeight(r.y + 1)
+	for x := 0; x < r.canvas.Bounds().Dx(); x += 2 * previewDashLength {
+		r.fill(x, r.y, previewDashLength, 1, 0)
+	}
+	r.y++
+}
+
+// fill paints a rectangle with the given gray level
+func (r *receiptRenderer) fill(x, y, w, h int, level uint8) {
+	rect := image.Rect(x, y, x+w, y+h).Intersect(r.canvas.Bounds())
+	draw.Draw(r.canvas, rect, &image.Uniform{C: color.Gray{Y: level}}, image.Point{}, draw.Src)
+}
+
+// ensureHeight grows the canvas to at least the given height
+func (r *receiptRenderer) ensureHeight(height int) {
+	r.ensureSize(r.canvas.Bounds().Dx(), height)
+}
+
+// ensureSize grows the canvas to at least the given size, filling new space with white
+func (r *receiptRenderer) ensureSize(width, height int) {
+	bounds := r.canvas.Bounds()
+	if width <= bounds.Dx() && height <= bounds.Dy() {
+		return
+	}
+
+	grown := image.NewGray(image.Rect(0, 0, max(width, bounds.Dx()), max(height, bounds.Dy())))
+	draw.Draw(grown, grown.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(grown, bounds, r.canvas, image.Point{}, draw.Src)
+	r.canvas = grown
+}
+
+// asciiRamp lists the characters used by ASCIIPreview from white to black
+const asciiRamp = " .+#"
+
+// ASCIIPreview renders a dithered image as ASCII art for a quick look in a terminal.
+//
+// The image is downsampled to at most maxWidth characters per line (80 if maxWidth
+// is not positive). Each character covers a block of pixels twice as high as wide,
+// matching the aspect ratio of terminal cells, and shows the share of black pixels
+// in the block: ' ' for white, '#' for black and '.' or '+' in between.
+func ASCIIPreview(img image.Image, maxWidth int) string {
+	if maxWidth <= 0 {
+		maxWidth = 80
+	}
+	bounds := img.Bounds()
+	cellWidth := max((bounds.Dx()+maxWidth-1)/maxWidth, 1)
+	cellHeight := 2 * cellWidth
+
+	var sb strings.Builder
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += cellHeight {
+		for x := bounds.Min.X; x < bounds.Max.X; x += cellWidth {
+			black, total := 0, 0
+			for cy := y; cy < min(y+cellHeight, bounds.Max.Y); cy++ {
+				for cx := x; cx < min(x+cellWidth, bounds.Max.X); cx++ {
+					if color.GrayModel.Convert(img.At(cx, cy)).(color.Gray).Y < 128 {
+						black++
+					}
+					total++
+				}
+			}
+			// Round the coverage to the nearest ramp character
+			level := (black*(len(asciiRamp)-1)*2 + total) / (2 * total)
+			sb.WriteByte(asciiRamp[level])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,127 @@
+package escposimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// blackColumns returns the x coordinates of the black dots in row y
+func blackColumns(img image.Image, y int) []int {
+	var cols []int
+	bounds := img.Bounds()
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y == 0 {
+			cols = append(cols, x)
+		}
+	}
+	return cols
+}
+
+func TestRenderESCPOSJustification(t *testing.T) {
+	config := DefaultConfig()
+	width := config.CalculatePixelWidth()
+
+	tests := []struct {
+		name  string
+		align byte
+		want  int
+	}{
+		{"left", 0, 0},
+		{"center", 1, (width - 8) / 2},
+		{"right", 2, width - 8},
+		{"center ASCII", '1', (width - 8) / 2},
+	}
+	for _, tt := range tests {
+		data := []byte{ESC, 'a', tt.align, GS, 'v', '0', 0, 1, 0, 1, 0, 0x80}
+		img, err := RenderESCPOS(data, config)
+		if err != nil {
+			t.Fatalf("%s: RenderESCPOS() error = %v", tt.name, err)
+		}
+		if got := blackColumns(img, 0); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: black dots at %v, want [%d]", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderESCPOSStar(t *testing.T) {
+	config := DefaultConfig()
+	config.Dialect = DialectStar
+
+	// ESC GS S m xL xH yL yH n: one byte per line, two lines, then a full cut
+	data := []byte{ESC, GS, 'S', 1, 1, 0, 2, 0, 0, 0xF0, 0x01, ESC, 'd', 0}
+	img, err := RenderESCPOS(data, config)
+	if err != nil {
+		t.Fatalf("RenderESCPOS() error = %v", err)
+	}
+	if got := blackColumns(img, 0); len(got) != 4 || got[0] != 0 || got[3] != 3 {
+		t.Errorf("row 0 black dots = %v, want [0 1 2 3]", got)
+	}
+	if got := blackColumns(img, 1); len(got) != 1 || got[0] != 7 {
+		t.Errorf("row 1 black dots = %v, want [7]", got)
+	}
+	if img.Bounds().Dy() <= 2 {
+		t.Errorf("height = %d, want the cut line below the image", img.Bounds().Dy())
+	}
+}
+
+func TestRenderESCPOSFeedLines(t *testing.T) {
+	config := DefaultConfig()
+	img, err := RenderESCPOS([]byte{ESC, 'd', 3, GS, 'v', '0', 0, 1, 0, 1, 0, 0x80}, config)
+	if err != nil {
+		t.Fatalf("RenderESCPOS() error = %v", err)
+	}
+	y := 3 * (config.VerticalDPI() / 6)
+	if got := blackColumns(img, y); len(got) != 1 {
+		t.Errorf("black dots at row %d = %v, want the image below three fed lines", y, got)
+	}
+}
+
+func TestRenderESCPOSDownloadedImage(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	src.SetGray(3, 0, color.Gray{})
+	src.SetGray(10, 5, color.Gray{})
+
+	var buf bytes.Buffer
+	writeDownloadedImage(&buf, src, RasterScaleNormal)
+	img, err := RenderESCPOS(buf.Bytes(), DefaultConfig())
+	if err != nil {
+		t.Fatalf("RenderESCPOS() error = %v", err)
+	}
+	for y := 0; y < 8; y++ {
+		var want []int
+		switch y {
+		case 0:
+			want = []int{3}
+		case 5:
+			want = []int{10}
+		}
+		if got := blackColumns(img, y); len(got) != len(want) || (len(want) > 0 && got[0] != want[0]) {
+			t.Errorf("row %d black dots = %v, want %v", y, got, want)
+		}
+	}
+}
+
+func TestRenderESCPOSUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"NV graphics by key", []byte{GS, '(', 'L', 6, 0, 48, 69, 'A', 'B', 1, 1}, "NV graphics"},
+		{"GS / without GS *", []byte{GS, '/', 0}, "GS *"},
+	}
+	for _, tt := range tests {
+		_, err := RenderESCPOS(tt.data, DefaultConfig())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package escposimg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoPreviousJob is returned by Printer.Reprint when nothing has been printed yet
+var ErrNoPreviousJob = errors.New("no previous print job to reprint")
+
+// Printer is a reusable printer handle that keeps its output open across jobs.
+//
+// Unlike ProcessImage, which closes the output after a single image, a Printer
+// can print any number of images to the same output and remembers the bytes of
+// the last job so it can be sent again with Reprint.
+type Printer struct {
+	config  *Config
+	output  OutputMethod
+	lastJob []byte
+}
+
+// NewPrinter creates a new printer using the given configuration and output method
+func NewPrinter(config *Config, output OutputMethod) *Printer {
+	return &Printer{
+		config: config,
+		output: output,
+	}
+}
+
+// Print processes an image and sends the resulting commands to the printer
+func (p *Printer) Print(imagePath string) error {
+	data, err := GenerateImageCommands(imagePath, p.config)
+	if err != nil {
+		return err
+	}
+	return p.send(data)
+}
+
+// Reprint re-sends the commands of the last job without re-running the pipeline.
+// Returns ErrNoPreviousJob if nothing has been printed yet.
+func (p *Printer) Reprint() error {
+	if p.lastJob == nil {
+		return ErrNoPreviousJob
+	}
+	logger().Debug("Reprinting last job", "data_size", len(p.lastJob))
+	if err := writeCommands(p.output, p.lastJob, p.config.InitDelay); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	return nil
+}
+
+// ReprintBuffered prints the image of the last job again from the printer's memory,
+// sending only a few bytes (see Config.PreserveBuffer and PrintBufferedImage).
+// Returns ErrNoPreviousJob if nothing has been printed yet.
+func (p *Printer) ReprintBuffered() error {
+	if p.lastJob == nil {
+		return ErrNoPreviousJob
+	}
+	if !p.config.PreserveBuffer {
+		return errors.New("reprinting from the printer's memory requires PreserveBuffer")
+	}
+	logger().Debug("Reprinting last job from the printer's memory")
+	if err := writeCommands(p.output, PrintBufferedImage(p.config), p.config.InitDelay); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying output method
+func (p *Printer) Close() error {
+	return p.output.Close()
+}
+
+// send writes data to the output and caches it as the last job
+func (p *Printer) send(data []byte) error {
+	if err := writeCommands(p.output, data, p.config.InitDelay); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	p.lastJob = data
+	logger().Debug("Print job sent", "data_size", len(data))
+	return nil
+}
@@ -2,7 +2,8 @@ package escposimg
 
 import (
 	"image"
-	"log/slog"
+	"image/color"
+	"math"
 
 	"github.com/nfnt/resize"
 )
@@ -10,29 +11,204 @@ import (
 // ScaleImage scales an image to the specified width while maintaining aspect ratio.
 // Uses Lanczos3 interpolation for high quality scaling.
 func ScaleImage(img image.Image, targetWidth int) (image.Image, error) {
+	return ScaleImageAspect(img, targetWidth, 1.0)
+}
+
+// ScaleImageAspect scales an image to the specified width and multiplies the resulting
+// height by verticalScale, compensating for printers with non-square dots.
+// A verticalScale of 1.0 behaves exactly like ScaleImage.
+func ScaleImageAspect(img image.Image, targetWidth int, verticalScale float64) (image.Image, error) {
+	return ScaleImageWithFilter(img, targetWidth, verticalScale, ScaleFilterLanczos3)
+}
+
+// ScaleImageWithFilter scales an image like ScaleImageAspect using the given filter
+func ScaleImageWithFilter(img image.Image, targetWidth int, verticalScale float64, filter ScaleFilter) (image.Image, error) {
+	return scaleImage(img, targetWidth, verticalScale, filter, HeightRoundNearest)
+}
+
+// ScaleImageConfig scales an image to the target width using the vertical scale,
+// filter and height rounding of the configuration
+func ScaleImageConfig(img image.Image, targetWidth int, config *Config) (image.Image, error) {
+	return scaleImage(img, targetWidth, config.VerticalScale(), config.ScaleFilter, config.HeightRounding)
+}
+
+// scaleImage implements the scaling functions
+func scaleImage(img image.Image, targetWidth int, verticalScale float64, filter ScaleFilter, rounding HeightRounding) (image.Image, error) {
 	bounds := img.Bounds()
 	originalWidth := bounds.Dx()
 	originalHeight := bounds.Dy()
 
 	// If the image is already the target width, return as-is
-	if originalWidth == targetWidth {
-		slog.Debug("Image already at target width, no scaling needed", "width", targetWidth)
+	if originalWidth == targetWidth && verticalScale == 1.0 {
+		logger().Debug("Image already at target width, no scaling needed", "width", targetWidth)
 		return img, nil
 	}
 
-	slog.Debug("Scaling image",
+	logger().Debug("Scaling image",
 		"original_width", originalWidth,
 		"original_height", originalHeight,
-		"target_width", targetWidth)
+		"target_width", targetWidth,
+		"vertical_scale", verticalScale,
+		"filter", filter.String(),
+		"height_rounding", rounding.String())
 
-	// Use Lanczos3 for high-quality scaling
-	// Height is set to 0 to preserve aspect ratio automatically
-	scaledImg := resize.Resize(uint(targetWidth), 0, img, resize.Lanczos3)
+	// The height is always passed explicitly so that its rounding is predictable
+	height := float64(originalHeight) * float64(targetWidth) / float64(originalWidth) * verticalScale
+	targetHeight := uint(math.Max(1, rounding.apply(height)))
+
+	var scaledImg image.Image
+	if filter == ScaleFilterAreaAverage && targetWidth < originalWidth {
+		scaledImg = areaAverage(img, targetWidth, int(targetHeight))
+	} else {
+		scaledImg = resize.Resize(uint(targetWidth), targetHeight, img, filter.interpolation())
+	}
 
 	newBounds := scaledImg.Bounds()
-	slog.Debug("Image scaled successfully",
+	logger().Debug("Image scaled successfully",
 		"new_width", newBounds.Dx(),
 		"new_height", newBounds.Dy())
 
 	return scaledImg, nil
 }
+
+// ScaleFilter selects the interpolation used when scaling images
+type ScaleFilter int
+
+const (
+	// ScaleFilterLanczos3 gives sharp, high-quality results (default).
+	// It can ring around hard edges when reducing images a lot.
+	ScaleFilterLanczos3 ScaleFilter = iota
+
+	// ScaleFilterBilinear gives smooth results with little ringing
+	ScaleFilterBilinear
+
+	// ScaleFilterNearestNeighbor keeps hard pixel edges, useful for pixel art
+	ScaleFilterNearestNeighbor
+
+	// ScaleFilterAreaAverage averages all source pixels covered by each target pixel.
+	// It gives the cleanest results for large photo reductions. When enlarging it
+	// falls back to bilinear interpolation.
+	ScaleFilterAreaAverage
+)
+
+// String returns the string representation of the scale filter
+func (f ScaleFilter) String() string {
+	switch f {
+	case ScaleFilterLanczos3:
+		return "lanczos3"
+	case ScaleFilterBilinear:
+		return "bilinear"
+	case ScaleFilterNearestNeighbor:
+		return "nearest"
+	case ScaleFilterAreaAverage:
+		return "area"
+	default:
+		return "unknown"
+	}
+}
+
+// interpolation returns the resize interpolation function for the filter
+func (f ScaleFilter) interpolation() resize.InterpolationFunction {
+	switch f {
+	case ScaleFilterBilinear, ScaleFilterAreaAverage:
+		return resize.Bilinear
+	case ScaleFilterNearestNeighbor:
+		return resize.NearestNeighbor
+	default:
+		return resize.Lanczos3
+	}
+}
+
+// HeightRounding selects how the scaled height is rounded to whole pixels
+type HeightRounding int
+
+const (
+	// HeightRoundNearest rounds to the nearest pixel (default)
+	HeightRoundNearest HeightRounding = iota
+	// HeightRoundDown rounds down
+	HeightRoundDown
+	// HeightRoundUp rounds up
+	HeightRoundUp
+)
+
+// String returns the string representation of the height rounding
+func (r HeightRounding) String() string {
+	switch r {
+	case HeightRoundNearest:
+		return "round"
+	case HeightRoundDown:
+		return "floor"
+	case HeightRoundUp:
+		return "ceil"
+	default:
+		return "unknown"
+	}
+}
+
+// apply rounds a height according to the rounding mode
+func (r HeightRounding) apply(height float64) float64 {
+	switch r {
+	case HeightRoundDown:
+		return math.Floor(height)
+	case HeightRoundUp:
+		return math.Ceil(height)
+	default:
+		return math.Round(height)
+	}
+}
+
+// areaAverage downscales an image with a box filter. Every target pixel is the
+// average of the source area it covers, weighting partially covered source pixels
+// by their overlap. The filter is separable and applied horizontally, then vertically.
+func areaAverage(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	srcWidth := bounds.Dx()
+	srcHeight := bounds.Dy()
+
+	// Horizontal pass: srcWidth x srcHeight -> width x srcHeight
+	tmp := make([][4]float64, width*srcHeight)
+	scaleX := float64(srcWidth) / float64(width)
+	for y := 0; y < srcHeight; y++ {
+		for x := 0; x < width; x++ {
+			start := float64(x) * scaleX
+			end := start + scaleX
+			var acc [4]float64
+			for sx := int(start); sx < srcWidth && float64(sx) < end; sx++ {
+				weight := math.Min(float64(sx+1), end) - math.Max(float64(sx), start)
+				r, g, b, a := img.At(sx+bounds.Min.X, y+bounds.Min.Y).RGBA()
+				acc[0] += float64(r) * weight
+				acc[1] += float64(g) * weight
+				acc[2] += float64(b) * weight
+				acc[3] += float64(a) * weight
+			}
+			for c := range acc {
+				tmp[y*width+x][c] = acc[c] / scaleX
+			}
+		}
+	}
+
+	// Vertical pass: width x srcHeight -> width x height
+	result := image.NewRGBA64(image.Rect(0, 0, width, height))
+	scaleY := float64(srcHeight) / float64(height)
+	for y := 0; y < height; y++ {
+		start := float64(y) * scaleY
+		end := start + scaleY
+		for x := 0; x < width; x++ {
+			var acc [4]float64
+			for sy := int(start); sy < srcHeight && float64(sy) < end; sy++ {
+				weight := math.Min(float64(sy+1), end) - math.Max(float64(sy), start)
+				for c := range acc {
+					acc[c] += tmp[sy*width+x][c] * weight
+				}
+			}
+			result.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(math.Min(acc[0]/scaleY, 0xFFFF)),
+				G: uint16(math.Min(acc[1]/scaleY, 0xFFFF)),
+				B: uint16(math.Min(acc[2]/scaleY, 0xFFFF)),
+				A: uint16(math.Min(acc[3]/scaleY, 0xFFFF)),
+			})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,80 @@
+package escposimg
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// checkerboard returns an image of alternating black and white pixels
+func checkerboard(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x+y)%2 == 1 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+// grayAt returns the gray level of a pixel
+func grayAt(img image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
+
+func TestScaleFilterAreaAverageCheckerboard(t *testing.T) {
+	// A 2:1 reduction covers two black and two white pixels with every target pixel
+	scaled, err := ScaleImageWithFilter(checkerboard(64, 64), 32, 1.0, ScaleFilterAreaAverage)
+	if err != nil {
+		t.Fatalf("ScaleImageWithFilter() error = %v", err)
+	}
+	if got := scaled.Bounds(); got.Dx() != 32 || got.Dy() != 32 {
+		t.Fatalf("scaled to %dx%d, want 32x32", got.Dx(), got.Dy())
+	}
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			if gray := grayAt(scaled, x, y); gray < 126 || gray > 129 {
+				t.Fatalf("pixel (%d,%d) = %d, want mid-gray", x, y, gray)
+			}
+		}
+	}
+}
+
+func TestScaleFilterAreaAverageEdge(t *testing.T) {
+	// Black left half, white right half, reduced 4:1 so the edge falls between target pixels
+	src := image.NewGray(image.Rect(0, 0, 64, 16))
+	for y := 0; y < 16; y++ {
+		for x := 32; x < 64; x++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	tests := []struct {
+		filter ScaleFilter
+		clean  bool
+	}{
+		{ScaleFilterAreaAverage, true},
+		{ScaleFilterLanczos3, false},
+	}
+	for _, tt := range tests {
+		scaled, err := ScaleImageWithFilter(src, 16, 1.0, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: ScaleImageWithFilter() error = %v", tt.filter, err)
+		}
+		clean := true
+		for x := 0; x < 16; x++ {
+			want := uint8(0)
+			if x >= 8 {
+				want = 255
+			}
+			if grayAt(scaled, x, 2) != want {
+				clean = false
+			}
+		}
+		if clean != tt.clean {
+			t.Errorf("%s: edge kept sharp = %v, want %v", tt.filter, clean, tt.clean)
+		}
+	}
+}
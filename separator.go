@@ -0,0 +1,76 @@
+package escposimg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// ParseSeparatorStyle returns the separator style with the given name as printed
+// by SeparatorStyle.String; an empty name selects SeparatorSolid
+func ParseSeparatorStyle(name string) (SeparatorStyle, error) {
+	if name == "" {
+		return SeparatorSolid, nil
+	}
+	for _, style := range SeparatorStyles() {
+		if style.String() == name {
+			return style, nil
+		}
+	}
+	return SeparatorSolid, fmt.Errorf("unknown separator style: %s", name)
+}
+
+// renderSeparator draws a separator of the given style, black on white
+func renderSeparator(style SeparatorStyle, width, height int) (*image.Gray, error) {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	black := &image.Uniform{C: color.Gray{Y: 0}}
+
+	switch style {
+	case SeparatorSolid:
+		draw.Draw(img, img.Bounds(), black, image.Point{}, draw.Src)
+		return img, nil
+	default:
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+
+	switch style {
+	case SeparatorDots:
+		// Dots as wide as the rule is high, one dot width apart
+		radius := float64(height) / 2
+		for cx := radius; cx+radius <= float64(width); cx += 2 * float64(height) {
+			for y := 0; y < height; y++ {
+				for x := int(cx - radius); x < int(math.Ceil(cx+radius)); x++ {
+					dx, dy := float64(x)+0.5-cx, float64(y)+0.5-radius
+					if dx*dx+dy*dy <= radius*radius {
+						img.SetGray(x, y, color.Gray{Y: 0})
+					}
+				}
+			}
+		}
+	case SeparatorDashes:
+		// Dashes three times and gaps twice as long as the rule is high
+		dash, period := 3*height, 5*height
+		for x := 0; x < width; x += period {
+			draw.Draw(img, image.Rect(x, 0, min(x+dash, width), height), black, image.Point{}, draw.Src)
+		}
+	case SeparatorWave:
+		// A line of a quarter of the height following a sine wave across the height
+		thickness := max(1, height/4)
+		amplitude := float64(height-thickness) / 2
+		period := 4 * float64(height)
+		for x := 0; x < width; x++ {
+			top := int(math.Round(amplitude - amplitude*math.Sin(2*math.Pi*float64(x)/period)))
+			draw.Draw(img, image.Rect(x, top, x+1, top+thickness), black, image.Point{}, draw.Src)
+		}
+	case SeparatorDoubleLine:
+		// Two lines of a third of the height at the top and bottom edges
+		thickness := max(1, height/3)
+		draw.Draw(img, image.Rect(0, 0, width, thickness), black, image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(0, height-thickness, width, height), black, image.Point{}, draw.Src)
+	default:
+		return nil, fmt.Errorf("unknown separator style %d", style)
+	}
+	return img, nil
+}
@@ -0,0 +1,151 @@
+package escposimg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Software flow control characters sent by serial printers
+const (
+	XON  = 0x11 // Resume transmission
+	XOFF = 0x13 // Pause transmission
+)
+
+// defaultFlowControlChunkSize is the chunk size used with software flow control
+// when SerialConfig.ChunkSize is zero, so that XOFF is honored within a write
+const defaultFlowControlChunkSize = 64
+
+// SerialConfig holds the pacing options of a SerialOutput
+type SerialConfig struct {
+	// Number of bytes written at once (zero writes all data at once, or chunks of
+	// 64 bytes with SoftwareFlowControl; 1 paces every byte)
+	ChunkSize int
+
+	// Pause after each chunk, giving printers without hardware flow control time
+	// to empty their receive buffer
+	ChunkDelay time.Duration
+
+	// Honor XON/XOFF software flow control: writing pauses when the printer sends
+	// XOFF (0x13) and resumes when it sends XON (0x11)
+	SoftwareFlowControl bool
+}
+
+// SerialOutput writes data to a serial port, e.g. /dev/ttyUSB0 or COM1.
+//
+// The port settings (baud rate, parity, etc.) are not changed and must be set up
+// beforehand, e.g. with stty. The output only paces the data as configured.
+type SerialOutput struct {
+	port   io.ReadWriteCloser
+	config SerialConfig
+
+	// Flow control state, updated by the goroutine reading from the port
+	mu     sync.Mutex
+	resume *sync.Cond
+	paused bool
+	closed bool
+}
+
+// NewSerialOutput opens a serial port as output method
+func NewSerialOutput(devicePath string, config SerialConfig) (*SerialOutput, error) {
+	port, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port %s: %w", devicePath, err)
+	}
+	return newSerialOutput(port, config), nil
+}
+
+// newSerialOutput creates a serial output writing to an opened port
+func newSerialOutput(port io.ReadWriteCloser, config SerialConfig) *SerialOutput {
+	s := &SerialOutput{port: port, config: config}
+	s.resume = sync.NewCond(&s.mu)
+	if config.SoftwareFlowControl {
+		go s.readFlowControl()
+	}
+	return s
+}
+
+// Write writes data to the serial port in chunks, waiting while the printer has
+// paused transmission with XOFF
+func (s *SerialOutput) Write(data []byte) error {
+	chunkSize := s.config.ChunkSize
+	if chunkSize <= 0 {
+		// A single write would pass XOFF by, as it is only checked between chunks
+		chunkSize = len(data)
+		if s.config.SoftwareFlowControl {
+			chunkSize = defaultFlowControlChunkSize
+		}
+	}
+
+	for len(data) > 0 {
+		if err := s.waitForXON(); err != nil {
+			return err
+		}
+
+		chunk := data[:min(chunkSize, len(data))]
+		if _, err := s.port.Write(chunk); err != nil {
+			return err
+		}
+		data = data[len(chunk):]
+
+		if s.config.ChunkDelay > 0 && len(data) > 0 {
+			time.Sleep(s.config.ChunkDelay)
+		}
+	}
+	return nil
+}
+
+// Close closes the serial port
+func (s *SerialOutput) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.resume.Broadcast()
+	s.mu.Unlock()
+	return s.port.Close()
+}
+
+// waitForXON blocks while transmission is paused by XOFF
+func (s *SerialOutput) waitForXON() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.paused && !s.closed {
+		s.resume.Wait()
+	}
+	if s.closed {
+		return os.ErrClosed
+	}
+	return nil
+}
+
+// readFlowControl reads XON/XOFF characters from the port until it is closed.
+// Other bytes, e.g. status responses, are ignored.
+func (s *SerialOutput) readFlowControl() {
+	buf := make([]byte, 64)
+	for {
+		n, err := s.port.Read(buf)
+		s.mu.Lock()
+		for _, b := range buf[:n] {
+			switch b {
+			case XOFF:
+				s.paused = true
+				logger().Debug("Serial printer paused transmission (XOFF)")
+			case XON:
+				s.paused = false
+				logger().Debug("Serial printer resumed transmission (XON)")
+			}
+		}
+		if err != nil {
+			// Without a readable port XON can never arrive, so stop pausing
+			logger().Debug("Stopped reading flow control from serial port", "error", err)
+			s.paused = false
+		}
+		s.resume.Broadcast()
+		s.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package escposimg
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSerialPort records writes and returns the flow control bytes sent on input
+type fakeSerialPort struct {
+	input   chan byte
+	onWrite func(n int)
+
+	mu     sync.Mutex
+	data   bytes.Buffer
+	chunks []int
+}
+
+func (p *fakeSerialPort) Read(buf []byte) (int, error) {
+	b, ok := <-p.input
+	if !ok {
+		return 0, io.EOF
+	}
+	buf[0] = b
+	return 1, nil
+}
+
+func (p *fakeSerialPort) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	p.data.Write(data)
+	p.chunks = append(p.chunks, len(data))
+	n := len(p.chunks)
+	p.mu.Unlock()
+	if p.onWrite != nil {
+		p.onWrite(n)
+	}
+	return len(data), nil
+}
+
+func (p *fakeSerialPort) Close() error {
+	close(p.input)
+	return nil
+}
+
+func (p *fakeSerialPort) written() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.data.Len()
+}
+
+// waitPaused waits until the output has processed the flow control state
+func waitPaused(t *testing.T, s *SerialOutput, paused bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		done := s.paused == paused
+		s.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("flow control state did not change to paused=%v", paused)
+}
+
+func TestSerialOutputChunks(t *testing.T) {
+	data := bytes.Repeat([]byte{0xAA}, 200)
+
+	tests := []struct {
+		name   string
+		config SerialConfig
+		chunks []int
+	}{
+		{"all at once", SerialConfig{}, []int{200}},
+		{"chunked", SerialConfig{ChunkSize: 80}, []int{80, 80, 40}},
+		{"flow control default", SerialConfig{SoftwareFlowControl: true}, []int{64, 64, 64, 8}},
+		{"flow control chunked", SerialConfig{SoftwareFlowControl: true, ChunkSize: 100}, []int{100, 100}},
+	}
+	for _, tt := range tests {
+		port := &fakeSerialPort{input: make(chan byte)}
+		s := newSerialOutput(port, tt.config)
+		if err := s.Write(data); err != nil {
+			t.Fatalf("%s: Write() error = %v", tt.name, err)
+		}
+		s.Close()
+		if !bytes.Equal(port.data.Bytes(), data) {
+			t.Errorf("%s: port received %d bytes, want the %d written", tt.name, port.data.Len(), len(data))
+		}
+		if len(port.chunks) != len(tt.chunks) {
+			t.Errorf("%s: chunks = %v, want %v", tt.name, port.chunks, tt.chunks)
+			continue
+		}
+		for i := range tt.chunks {
+			if port.chunks[i] != tt.chunks[i] {
+				t.Errorf("%s: chunks = %v, want %v", tt.name, port.chunks, tt.chunks)
+				break
+			}
+		}
+	}
+}
+
+func TestSerialOutputXONXOFF(t *testing.T) {
+	port := &fakeSerialPort{input: make(chan byte, 1)}
+	s := newSerialOutput(port, SerialConfig{SoftwareFlowControl: true})
+
+	// The printer sends XOFF while the first chunk is transmitted
+	port.onWrite = func(n int) {
+		if n == 1 {
+			port.input <- XOFF
+			waitPaused(t, s, true)
+		}
+	}
+
+	done := make(chan error)
+	go func() { done <- s.Write(bytes.Repeat([]byte{0x55}, 200)) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if got := port.written(); got != defaultFlowControlChunkSize {
+		t.Errorf("%d bytes written while paused, want only the first chunk of %d", got, defaultFlowControlChunkSize)
+	}
+
+	port.input <- XON
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Write() did not resume after XON")
+	}
+	if got := port.written(); got != 200 {
+		t.Errorf("%d bytes written after XON, want 200", got)
+	}
+
+	// Closing the port releases a write waiting for XON
+	port.input <- XOFF
+	waitPaused(t, s, true)
+	go func() { done <- s.Write([]byte{0x55}) }()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Write() on a closed output returned no error")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Write() stayed blocked after Close")
+	}
+}
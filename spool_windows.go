@@ -0,0 +1,93 @@
+//go:build windows
+
+package escposimg
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Functions of the Windows print spooler API
+var (
+	winspool             = syscall.NewLazyDLL("winspool.drv")
+	procOpenPrinter      = winspool.NewProc("OpenPrinterW")
+	procClosePrinter     = winspool.NewProc("ClosePrinter")
+	procStartDocPrinter  = winspool.NewProc("StartDocPrinterW")
+	procEndDocPrinter    = winspool.NewProc("EndDocPrinter")
+	procStartPagePrinter = winspool.NewProc("StartPagePrinter")
+	procEndPagePrinter   = winspool.NewProc("EndPagePrinter")
+	procWritePrinter     = winspool.NewProc("WritePrinter")
+)
+
+// docInfo1 is the DOC_INFO_1 structure describing a print job
+type docInfo1 struct {
+	docName    *uint16
+	outputFile *uint16
+	datatype   *uint16
+}
+
+// WindowsSpoolOutput submits data as a RAW job to a printer installed in Windows,
+// so the spooler passes the ESC/POS commands to the printer unchanged. The job
+// is started when the output is created and printed when it is closed.
+type WindowsSpoolOutput struct {
+	printer syscall.Handle
+	name    string
+}
+
+// NewWindowsSpoolOutput opens the Windows printer with the given name (as shown
+// in the printer settings) and starts a RAW print job
+func NewWindowsSpoolOutput(printerName string) (*WindowsSpoolOutput, error) {
+	name, err := syscall.UTF16PtrFromString(printerName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid printer name %q: %w", printerName, err)
+	}
+
+	var printer syscall.Handle
+	if ok, _, err := procOpenPrinter.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&printer)), 0); ok == 0 {
+		return nil, fmt.Errorf("failed to open printer %q: %w", printerName, err)
+	}
+
+	doc := docInfo1{
+		docName:  syscall.StringToUTF16Ptr("escposimg"),
+		datatype: syscall.StringToUTF16Ptr("RAW"),
+	}
+	if job, _, err := procStartDocPrinter.Call(uintptr(printer), 1, uintptr(unsafe.Pointer(&doc))); job == 0 {
+		procClosePrinter.Call(uintptr(printer))
+		return nil, fmt.Errorf("failed to start print job on %q: %w", printerName, err)
+	}
+	if ok, _, err := procStartPagePrinter.Call(uintptr(printer)); ok == 0 {
+		procEndDocPrinter.Call(uintptr(printer))
+		procClosePrinter.Call(uintptr(printer))
+		return nil, fmt.Errorf("failed to start page on %q: %w", printerName, err)
+	}
+
+	logger().Debug("Started RAW print job", "printer", printerName)
+	return &WindowsSpoolOutput{printer: printer, name: printerName}, nil
+}
+
+// Write adds data to the print job
+func (w *WindowsSpoolOutput) Write(data []byte) error {
+	for len(data) > 0 {
+		var written uint32
+		ok, _, err := procWritePrinter.Call(uintptr(w.printer), uintptr(unsafe.Pointer(&data[0])),
+			uintptr(len(data)), uintptr(unsafe.Pointer(&written)))
+		if ok == 0 {
+			return fmt.Errorf("failed to write to printer %q: %w", w.name, err)
+		}
+		data = data[written:]
+	}
+	return nil
+}
+
+// Close ends the print job, releasing it for printing, and closes the printer
+func (w *WindowsSpoolOutput) Close() error {
+	defer procClosePrinter.Call(uintptr(w.printer))
+	if ok, _, err := procEndPagePrinter.Call(uintptr(w.printer)); ok == 0 {
+		return fmt.Errorf("failed to end page on %q: %w", w.name, err)
+	}
+	if ok, _, err := procEndDocPrinter.Call(uintptr(w.printer)); ok == 0 {
+		return fmt.Errorf("failed to end print job on %q: %w", w.name, err)
+	}
+	return nil
+}
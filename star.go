@@ -0,0 +1,109 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+)
+
+// maxStarRasterHeight is the number of dots sent per ESC GS S command. Taller
+// images are split into several commands to stay within the receive buffer of
+// smaller Star printers.
+const maxStarRasterHeight = 256
+
+// generateStarRaster generates StarPRNT commands for Star Micronics printers,
+// printing the image with the raster command ESC GS S and cutting with ESC d
+func generateStarRaster(img image.Image, config *Config) ([]byte, error) {
+	bounds := img.Bounds()
+	logger().Debug("Generating Star raster commands", "width", bounds.Dx(), "height", bounds.Dy())
+
+	if config.PrintMode != PrintModeRaster {
+		if err := config.warn("Print mode is ignored for Star printers, printing in raster mode",
+			"print_mode", config.PrintMode.String()); err != nil {
+			return nil, err
+		}
+	}
+	if config.Alignment != AlignLeft {
+		if err := config.warn("Alignment is not supported for Star printers and will be ignored",
+			"alignment", config.Alignment.String()); err != nil {
+			return nil, err
+		}
+	}
+	if config.RasterScale != RasterScaleNormal {
+		if err := config.warn("Raster scale is not supported by Star printers and will be ignored",
+			"raster_scale", config.RasterScale.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+
+	// Initialize printer (ESC @)
+	writePrinterInit(&buf, config)
+
+	if config.DebugText != "" {
+		writeTextLine(&buf, config.DebugText, config)
+		logger().Debug("Added debug text", "text", config.DebugText)
+	}
+
+	if config.TopRule {
+		if err := writeRule(&buf, img, config); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeStarRasterImage(&buf, img, config); err != nil {
+		return nil, err
+	}
+	if config.BottomRule {
+		if err := writeRule(&buf, img, config); err != nil {
+			return nil, err
+		}
+	}
+	if config.BarcodeData != "" {
+		if err := config.warn("Barcodes are not supported for Star printers and will be ignored"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Feed paper and cut if requested
+	writeFinalFeed(&buf, 3, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Star raster command generation completed", "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
+
+// writeStarRasterImage converts an image to raster format and writes it as one or
+// more StarPRNT raster commands
+func writeStarRasterImage(buf *bytes.Buffer, img image.Image, config *Config) error {
+	img, err := fitToPaper(img, config)
+	if err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+
+	rasterData, err := convertToRasterFormat(img)
+	if err != nil {
+		return fmt.Errorf("failed to convert image to raster format: %w", err)
+	}
+
+	bytesPerLine := (bounds.Dx() + 7) / 8
+	if bytesPerLine > 0xFFFF {
+		return fmt.Errorf("raster width of %d bytes exceeds the maximum of %d", bytesPerLine, 0xFFFF)
+	}
+	for start := 0; start < bounds.Dy(); start += maxStarRasterHeight {
+		height := min(bounds.Dy()-start, maxStarRasterHeight)
+
+		// ESC GS S m xL xH yL yH n [data], m=1 (fixed), n=0 (monochrome)
+		buf.Write([]byte{ESC, GS, 'S', 1})
+		buf.Write([]byte{byte(bytesPerLine & 0xFF), byte((bytesPerLine >> 8) & 0xFF)})
+		buf.Write([]byte{byte(height & 0xFF), byte((height >> 8) & 0xFF)})
+		buf.WriteByte(0)
+		buf.Write(rasterData[start*bytesPerLine : (start+height)*bytesPerLine])
+	}
+
+	logger().Debug("Wrote Star raster image", "width_bytes", bytesPerLine, "height", bounds.Dy())
+	return nil
+}
@@ -0,0 +1,74 @@
+package escposimg
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// RenderTextImage renders text in a TrueType or OpenType font as black on a white
+// image, which can then be printed like any other image, e.g. with ProcessImageValue.
+// This allows labels in fonts the printer does not have built in.
+//
+// fontPath names a .ttf or .otf file; an empty path uses the Go Regular font.
+// sizePx is the font size in pixels (em height). Lines are separated by "\n"
+// and left-aligned; the image is as wide as the longest line.
+func RenderTextImage(text string, fontPath string, sizePx int) (image.Image, error) {
+	if sizePx <= 0 {
+		return nil, fmt.Errorf("invalid font size %d px", sizePx)
+	}
+
+	fontData := goregular.TTF
+	if fontPath != "" {
+		var err error
+		fontData, err = os.ReadFile(fontPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read font: %w", err)
+		}
+	}
+	parsed, err := opentype.Parse(fontData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{
+		Size:    float64(sizePx),
+		DPI:     72, // One point per pixel
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+	defer face.Close()
+
+	lines := strings.Split(text, "\n")
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+
+	width := 0
+	for _, line := range lines {
+		width = max(width, font.MeasureString(face, line).Ceil())
+	}
+	height := (len(lines)-1)*lineHeight + metrics.Ascent.Ceil() + metrics.Descent.Ceil()
+	if width == 0 || height <= 0 {
+		return nil, fmt.Errorf("text %q renders to an empty image", text)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{Dst: img, Src: image.Black, Face: face}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(0, metrics.Ascent.Ceil()+i*lineHeight)
+		drawer.DrawString(line)
+	}
+
+	logger().Debug("Rendered text image", "lines", len(lines), "width", width, "height", height)
+	return img, nil
+}
@@ -0,0 +1,180 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Dimensions used when laying out a ticket
+const (
+	// DefaultTicketBarcodeHeight is the bar height in dots used when
+	// TicketConfig.BarcodeHeightDots is zero
+	DefaultTicketBarcodeHeight = 162
+
+	// ticketHRIHeight is the height in dots of the human readable text printed
+	// below the barcode (one line of Font A)
+	ticketHRIHeight = 24
+)
+
+// TicketConfig describes a fixed-length ticket printed by ProcessTicket
+type TicketConfig struct {
+	// Processing and printer configuration (DefaultConfig if nil)
+	Config *Config
+
+	// Length of the ticket in millimeters, from the top of the image to the cut
+	HeightMM float64
+
+	// Optional image printed at the top of the ticket, e.g. a logo
+	TopImagePath string
+
+	// Optional barcode printed at the bottom of the ticket. BarcodeType is the
+	// GS k barcode system from 65 (UPC-A) to 73 (CODE128).
+	BarcodeData string
+	BarcodeType int
+
+	// Height of the barcode bars in dots (default: Config.BarcodeHeightDots if
+	// set, else DefaultTicketBarcodeHeight). The position of the human readable
+	// text is taken from Config.BarcodeHRI.
+	BarcodeHeightDots int
+}
+
+// ProcessTicket prints a fixed-length ticket with an image anchored at the top and
+// a barcode anchored at the bottom. See GenerateTicketCommands.
+func ProcessTicket(ticket *TicketConfig, output OutputMethod) error {
+	data, err := GenerateTicketCommands(ticket)
+	if err != nil {
+		return err
+	}
+
+	config := ticket.Config
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := writeCommands(output, data, config.InitDelay); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	logger().Info("Ticket processing completed successfully")
+	return nil
+}
+
+// GenerateTicketCommands generates the commands for a ticket of ticket.HeightMM:
+// the dithered top image, blank paper filling the middle, the barcode and a cut
+// directly below it, so every ticket has the same length.
+//
+// The paper is fed with ESC J, which assumes the printer's vertical motion unit
+// equals its dot pitch (true for most thermal printers). The length is measured
+// from the first printed dot; printers whose cutter sits ahead of the print head
+// add that distance as blank paper at the top of the first ticket of a job.
+func GenerateTicketCommands(ticket *TicketConfig) ([]byte, error) {
+	config := ticket.Config
+	if config == nil {
+		config = DefaultConfig()
+	}
+	totalDots := int(math.Round(ticket.HeightMM / 25.4 * float64(config.VerticalDPI())))
+	if totalDots <= 0 {
+		return nil, fmt.Errorf("invalid ticket height %.1f mm", ticket.HeightMM)
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+	usedDots := 0
+
+	// Top: the image
+	if ticket.TopImagePath != "" {
+		// Bit image bands advance by the line spacing, so their height is not known exactly
+		if config.PrintMode != PrintModeRaster {
+			if err := config.warn("Ticket length is only exact in raster mode", "print_mode", config.PrintMode.String()); err != nil {
+				return nil, err
+			}
+		}
+		img, err := PrepareImage(ticket.TopImagePath, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeImage(&buf, img, config); err != nil {
+			return nil, err
+		}
+		usedDots += printedHeight(img.Bounds().Dy(), config)
+	}
+
+	// Bottom: the barcode with its human readable text
+	barcodeHeight := ticket.BarcodeHeightDots
+	if barcodeHeight <= 0 {
+		barcodeHeight = config.BarcodeHeightDots
+	}
+	if barcodeHeight <= 0 {
+		barcodeHeight = DefaultTicketBarcodeHeight
+	}
+	if barcodeHeight > 255 {
+		return nil, fmt.Errorf("barcode height of %d dots exceeds the maximum of 255", barcodeHeight)
+	}
+	barcodeDots := 0
+	if ticket.BarcodeData != "" {
+		barcodeDots = barcodeHeight + ticketHRILines(config.BarcodeHRI)*ticketHRIHeight
+	}
+
+	// Middle: blank paper so that the cut lands at the ticket length
+	fillDots := totalDots - usedDots - barcodeDots
+	if fillDots < 0 {
+		return nil, fmt.Errorf("ticket content of %d dots exceeds the ticket height of %d dots (%.1f mm)",
+			usedDots+barcodeDots, totalDots, ticket.HeightMM)
+	}
+	writeFeedDots(&buf, fillDots)
+	logger().Debug("Ticket layout", "total_dots", totalDots, "image_dots", usedDots,
+		"fill_dots", fillDots, "barcode_dots", barcodeDots)
+
+	if ticket.BarcodeData != "" {
+		barcodeConfig := *config
+		barcodeConfig.BarcodeHeightDots = barcodeHeight
+		if err := writeBarcodeConfig(&buf, BarcodeType(ticket.BarcodeType), ticket.BarcodeData, &barcodeConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	if config.NoCutter {
+		// Without a cutter the ticket is torn off at the tear bar
+		if config.TearLine {
+			writeTearLine(&buf, config)
+		}
+	} else {
+		// GS V 66 0 (partial) or GS V 65 0 (full): feed the last printed line to
+		// the cutter and cut
+		if config.cutType() == CutFull {
+			buf.Write([]byte{GS, 'V', 65, 0})
+		} else {
+			buf.Write([]byte{GS, 'V', 66, 0})
+		}
+	}
+
+	logger().Debug("Ticket command generation completed", "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
+
+// printedHeight returns the height in dots an image of the given height occupies
+// on paper, accounting for the printer-side enlargement of raster images
+func printedHeight(height int, config *Config) int {
+	if config.PrintMode == PrintModeRaster && (config.RasterScale == RasterScaleDoubleHeight || config.RasterScale == RasterScaleQuadruple) {
+		return 2 * height
+	}
+	return height
+}
+
+// ticketHRILines returns the number of human readable text lines printed with the
+// barcode for the given position
+func ticketHRILines(hri HRIPosition) int {
+	switch hri {
+	case HRINone:
+		return 0
+	case HRIBoth:
+		return 2
+	default:
+		return 1
+	}
+}
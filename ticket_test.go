@@ -0,0 +1,87 @@
+package escposimg
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestGenerateTicketCommandsBarcode(t *testing.T) {
+	tests := []struct {
+		name     string
+		height   int
+		config   func(*Config)
+		want     []byte
+		hriLines int
+	}{
+		{"default", 0, func(c *Config) {},
+			[]byte{GS, 'h', 162, GS, 'H', 2, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}, 1},
+		{"ticket height", 100, func(c *Config) { c.BarcodeHeightDots = 50 },
+			[]byte{GS, 'h', 100, GS, 'H', 2, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}, 1},
+		{"config height", 0, func(c *Config) { c.BarcodeHeightDots = 50 },
+			[]byte{GS, 'h', 50, GS, 'H', 2, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}, 1},
+		{"no hri", 0, func(c *Config) { c.BarcodeHRI = HRINone },
+			[]byte{GS, 'h', 162, GS, 'H', 0, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}, 0},
+		{"both hri", 0, func(c *Config) { c.BarcodeHRI = HRIBoth },
+			[]byte{GS, 'h', 162, GS, 'H', 3, GS, 'k', 73, 5, '{', 'B', 'A', 'B', 'C'}, 2},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+		ticket := &TicketConfig{
+			Config:            config,
+			HeightMM:          50,
+			BarcodeData:       "ABC",
+			BarcodeType:       int(BarcodeCODE128),
+			BarcodeHeightDots: tt.height,
+		}
+		data, err := GenerateTicketCommands(ticket)
+		if err != nil {
+			t.Fatalf("%s: GenerateTicketCommands() error = %v", tt.name, err)
+		}
+		if bytes.Count(data, []byte{GS, 'h'}) != 1 || !bytes.Contains(data, tt.want) {
+			t.Errorf("%s: output does not contain a single % X", tt.name, tt.want)
+		}
+
+		// The blank fill keeps the ticket length: fill + bars + HRI text
+		fill := 0
+		commands, err := ParseESCPOS(data)
+		if err != nil {
+			t.Fatalf("%s: ParseESCPOS() error = %v", tt.name, err)
+		}
+		for _, cmd := range commands {
+			if cmd.Name == "ESC J" {
+				fill += int(cmd.Params[0])
+			}
+		}
+		height := tt.want[2]
+		total := int(math.Round(50 / 25.4 * float64(config.VerticalDPI())))
+		if got := fill + int(height) + tt.hriLines*ticketHRIHeight; got != total {
+			t.Errorf("%s: ticket length = %d dots, want %d", tt.name, got, total)
+		}
+	}
+}
+
+func TestTicketCutBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		cutType CutType
+		want    []byte
+	}{
+		// A ticket always ends with a feed-and-cut, which sets its length
+		{"default", CutNone, []byte{GS, 'V', 66, 0}},
+		{"partial", CutPartial, []byte{GS, 'V', 66, 0}},
+		{"full", CutFull, []byte{GS, 'V', 65, 0}},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.CutType = tt.cutType
+		data, err := GenerateTicketCommands(&TicketConfig{Config: config, HeightMM: 30})
+		if err != nil {
+			t.Fatalf("%s: GenerateTicketCommands() error = %v", tt.name, err)
+		}
+		if !bytes.HasSuffix(data, tt.want) || bytes.Count(data, []byte{GS, 'V'}) != 1 {
+			t.Errorf("%s: output does not end with a single % X", tt.name, tt.want)
+		}
+	}
+}
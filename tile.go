@@ -0,0 +1,179 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Size of the registration marks drawn above and below each tile, in dots
+const (
+	registrationMarkLength    = 16
+	registrationMarkThickness = 2
+)
+
+// SplitImage splits an image into vertical tiles of at most tileHeight pixels.
+// The last tile holds the remaining rows and may be shorter.
+func SplitImage(img image.Image, tileHeight int) ([]image.Image, error) {
+	if tileHeight <= 0 {
+		return nil, fmt.Errorf("tile height must be positive, got %d", tileHeight)
+	}
+
+	bounds := img.Bounds()
+	var tiles []image.Image
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += tileHeight {
+		rect := image.Rect(bounds.Min.X, y, bounds.Max.X, min(y+tileHeight, bounds.Max.Y))
+		tile := image.NewGray(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+		draw.Draw(tile, tile.Bounds(), img, rect.Min, draw.Src)
+		tiles = append(tiles, tile)
+	}
+	return tiles, nil
+}
+
+// AddRegistrationMarks adds a strip above and below a tile with crop-corner ticks
+// at both edges, marking the tile boundaries so printed pieces can be aligned.
+func AddRegistrationMarks(tile image.Image) image.Image {
+	bounds := tile.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	canvas := image.NewGray(image.Rect(0, 0, width, height+2*registrationMarkLength))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(0, registrationMarkLength, width, registrationMarkLength+height), tile, bounds.Min, draw.Src)
+
+	length := min(registrationMarkLength, width/2)
+	top := registrationMarkLength - registrationMarkThickness
+	bottom := registrationMarkLength + height
+	black := &image.Uniform{C: color.Gray{Y: 0}}
+	marks := []image.Rectangle{
+		// Horizontal ticks along the tile boundaries
+		image.Rect(0, top, length, top+registrationMarkThickness),
+		image.Rect(width-length, top, width, top+registrationMarkThickness),
+		image.Rect(0, bottom, length, bottom+registrationMarkThickness),
+		image.Rect(width-length, bottom, width, bottom+registrationMarkThickness),
+		// Vertical ticks pointing away from the tile
+		image.Rect(0, 0, registrationMarkThickness, registrationMarkLength),
+		image.Rect(width-registrationMarkThickness, 0, width, registrationMarkLength),
+		image.Rect(0, bottom, registrationMarkThickness, bottom+registrationMarkLength),
+		image.Rect(width-registrationMarkThickness, bottom, width, bottom+registrationMarkLength),
+	}
+	for _, mark := range marks {
+		draw.Draw(canvas, mark, black, image.Point{}, draw.Src)
+	}
+
+	return canvas
+}
+
+// GenerateTiledESCPOS splits a dithered image into tiles of config.TileHeightPx
+// and generates a separate receipt for each, so that tall images can be printed
+// in pieces. With config.RegistrationMarks each tile is framed by alignment marks.
+func GenerateTiledESCPOS(img image.Image, config *Config) ([]byte, error) {
+	tiles, err := SplitImage(img, config.TileHeightPx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The barcode is printed once, below the last tile
+	tileConfig := *config
+	tileConfig.BarcodeData = ""
+
+	var buf bytes.Buffer
+	for i, tile := range tiles {
+		if config.RegistrationMarks {
+			tile = AddRegistrationMarks(tile)
+		}
+		if i == len(tiles)-1 {
+			tileConfig.BarcodeData = config.BarcodeData
+		}
+		data, err := GenerateESCPOS(tile, &tileConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tile %d: %w", i+1, err)
+		}
+		buf.Write(data)
+	}
+
+	logger().Debug("Tiled command generation completed", "tiles", len(tiles), "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
+
+// StreamOrderedRaster prints a large image with little memory: it is dithered
+// with an ordered algorithm (DitheringThreshold, DitheringBayer or
+// DitheringHalftone, from config.DitheringAlgo) in tiles of tileHeight rows, and
+// each tile is written to output as its own GS v 0 command before the next one
+// is converted. Only one tile is held as gray values and packed raster bytes.
+// Error diffusion carries errors across the whole image and cannot be tiled.
+//
+// The image is printed as is, so it must not be wider than the paper; the layout
+// options (scaling, padding, rotation, mirroring) are not applied. The output is
+// not closed.
+func StreamOrderedRaster(img image.Image, config *Config, tileHeight int, output OutputMethod) error {
+	if tileHeight <= 0 {
+		return fmt.Errorf("tile height must be positive, got %d", tileHeight)
+	}
+	if config.Dialect != DialectEpson {
+		return fmt.Errorf("tiled raster streaming is not supported for the %s dialect", config.Dialect)
+	}
+	bounds := img.Bounds()
+	if paperWidth := config.CalculatePixelWidth(); bounds.Dx() > paperWidth {
+		return fmt.Errorf("image width of %d px exceeds the paper width of %d px", bounds.Dx(), paperWidth)
+	}
+	if err := validateRasterScale(config); err != nil {
+		return err
+	}
+
+	p := newDitherParams(config)
+	threshold, err := orderedThreshold(config.DitheringAlgo, p)
+	if err != nil {
+		return fmt.Errorf("tiled dithering requires an ordered algorithm: %w", err)
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	bytesPerLine := (bounds.Dx() + 7) / 8
+	for top := bounds.Min.Y; top < bounds.Max.Y; top += tileHeight {
+		rect := image.Rect(bounds.Min.X, top, bounds.Max.X, min(top+tileHeight, bounds.Max.Y))
+		gray := p.grayscale(imageRegion{img, rect})
+
+		// Pack the tile, with the matrix aligned to the whole image
+		raster := make([]byte, rect.Dy()*bytesPerLine)
+		for y := range gray {
+			for x, v := range gray[y] {
+				if int(v) < threshold(x, top-bounds.Min.Y+y) {
+					raster[y*bytesPerLine+x/8] |= 0x80 >> (x % 8)
+				}
+			}
+		}
+		if err := writeRasterImageCommand(&buf, rect.Dx(), rect.Dy(), config.RasterScale, raster); err != nil {
+			return fmt.Errorf("failed to write raster image command: %w", err)
+		}
+		if err := output.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write to output: %w", err)
+		}
+		buf.Reset()
+	}
+
+	writeSmoothingReset(&buf, config)
+	writeFinalFeed(&buf, 3, config)
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+	if err := output.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+
+	logger().Debug("Tiled raster streaming completed", "width", bounds.Dx(), "height", bounds.Dy(), "tile_height", tileHeight)
+	return nil
+}
+
+// imageRegion restricts an image to a rectangle, like the SubImage method of the
+// concrete image types
+type imageRegion struct {
+	image.Image
+	rect image.Rectangle
+}
+
+// Bounds returns the rectangle of the region
+func (r imageRegion) Bounds() image.Rectangle {
+	return r.rect
+}
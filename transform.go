@@ -0,0 +1,152 @@
+package escposimg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// AddPadding places an image on a white canvas with the given whitespace on each side
+func AddPadding(img image.Image, padding Padding) image.Image {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0,
+		padding.Left+bounds.Dx()+padding.Right,
+		padding.Top+bounds.Dy()+padding.Bottom))
+
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	target := image.Rect(padding.Left, padding.Top, padding.Left+bounds.Dx(), padding.Top+bounds.Dy())
+	draw.Draw(canvas, target, img, bounds.Min, draw.Src)
+
+	return canvas
+}
+
+// invertImage returns the negative of an image, keeping its alpha channel
+func invertImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	inverted := image.NewRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			// Colors are premultiplied, so the inverse of a channel is alpha minus its value
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			inverted.SetRGBA64(x, y, color.RGBA64{R: uint16(a - r), G: uint16(a - g), B: uint16(a - b), A: uint16(a)})
+		}
+	}
+
+	return inverted
+}
+
+// FlipImage mirrors an image horizontally (left-right) and/or vertically (top-bottom)
+func FlipImage(img image.Image, horizontal, vertical bool) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	flipped := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		srcY := y
+		if vertical {
+			srcY = height - 1 - y
+		}
+		for x := 0; x < width; x++ {
+			srcX := x
+			if horizontal {
+				srcX = width - 1 - x
+			}
+			flipped.Set(x, y, img.At(bounds.Min.X+srcX, bounds.Min.Y+srcY))
+		}
+	}
+
+	return flipped
+}
+
+// RotateImage rotates an image clockwise by 0, 90, 180 or 270 degrees without
+// resampling. Other angles return an error; see RotateImageArbitrary.
+func RotateImage(img image.Image, degrees int) (image.Image, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var rotated *image.RGBA
+	switch degrees {
+	case 0:
+		return img, nil
+	case 90, 270:
+		rotated = image.NewRGBA(image.Rect(0, 0, height, width))
+	case 180:
+		rotated = image.NewRGBA(image.Rect(0, 0, width, height))
+	default:
+		return nil, fmt.Errorf("unsupported rotation of %d degrees (expected 0, 90, 180 or 270)", degrees)
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			switch degrees {
+			case 90:
+				rotated.Set(height-1-y, x, c)
+			case 180:
+				rotated.Set(width-1-x, height-1-y, c)
+			case 270:
+				rotated.Set(y, width-1-x, c)
+			}
+		}
+	}
+
+	return rotated, nil
+}
+
+// RotateImageArbitrary rotates an image clockwise by the given angle in degrees
+// using bilinear sampling. The canvas is enlarged to hold the whole rotated image
+// and the uncovered corners are filled with bg.
+func RotateImageArbitrary(img image.Image, degrees float64, bg color.Color) image.Image {
+	bounds := img.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+
+	newWidth := int(math.Ceil(math.Abs(width*cos) + math.Abs(height*sin) - 1e-9))
+	newHeight := int(math.Ceil(math.Abs(width*sin) + math.Abs(height*cos) - 1e-9))
+	rotated := image.NewRGBA64(image.Rect(0, 0, newWidth, newHeight))
+
+	br, bgG, bb, ba := bg.RGBA()
+	background := [4]float64{float64(br), float64(bgG), float64(bb), float64(ba)}
+
+	// sample returns the premultiplied color of a source pixel, or the background outside the image
+	sample := func(x, y int) [4]float64 {
+		if x < 0 || y < 0 || x >= bounds.Dx() || y >= bounds.Dy() {
+			return background
+		}
+		r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return [4]float64{float64(r), float64(g), float64(b), float64(a)}
+	}
+
+	srcCX, srcCY := width/2, height/2
+	dstCX, dstCY := float64(newWidth)/2, float64(newHeight)/2
+	for y := 0; y < newHeight; y++ {
+		for x := 0; x < newWidth; x++ {
+			// Map the destination pixel center back into the source image
+			dx, dy := float64(x)+0.5-dstCX, float64(y)+0.5-dstCY
+			sx := dx*cos + dy*sin + srcCX - 0.5
+			sy := -dx*sin + dy*cos + srcCY - 0.5
+
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			if x0 < -1 || y0 < -1 || x0 >= bounds.Dx() || y0 >= bounds.Dy() {
+				rotated.Set(x, y, bg)
+				continue
+			}
+
+			c00, c10 := sample(x0, y0), sample(x0+1, y0)
+			c01, c11 := sample(x0, y0+1), sample(x0+1, y0+1)
+			var c [4]uint16
+			for i := range c {
+				top := c00[i]*(1-fx) + c10[i]*fx
+				bottom := c01[i]*(1-fx) + c11[i]*fx
+				c[i] = uint16(math.Round(top*(1-fy) + bottom*fy))
+			}
+			rotated.SetRGBA64(x, y, color.RGBA64{R: c[0], G: c[1], B: c[2], A: c[3]})
+		}
+	}
+
+	return rotated
+}
@@ -0,0 +1,83 @@
+package escposimg
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRotateImage(t *testing.T) {
+	// A 2x1 image: black on the left, white on the right
+	src := image.NewGray(image.Rect(0, 0, 2, 1))
+	src.SetGray(1, 0, color.Gray{Y: 255})
+
+	tests := []struct {
+		degrees       int
+		width, height int
+		black         image.Point
+		white         image.Point
+	}{
+		{0, 2, 1, image.Pt(0, 0), image.Pt(1, 0)},
+		{90, 1, 2, image.Pt(0, 0), image.Pt(0, 1)},
+		{180, 2, 1, image.Pt(1, 0), image.Pt(0, 0)},
+		{270, 1, 2, image.Pt(0, 1), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		rotated, err := RotateImage(src, tt.degrees)
+		if err != nil {
+			t.Fatalf("%d degrees: RotateImage() error = %v", tt.degrees, err)
+		}
+		if got := rotated.Bounds(); got.Dx() != tt.width || got.Dy() != tt.height {
+			t.Errorf("%d degrees: size %dx%d, want %dx%d", tt.degrees, got.Dx(), got.Dy(), tt.width, tt.height)
+			continue
+		}
+		if got := grayAt(rotated, tt.black.X, tt.black.Y); got != 0 {
+			t.Errorf("%d degrees: pixel %v = %d, want black", tt.degrees, tt.black, got)
+		}
+		if got := grayAt(rotated, tt.white.X, tt.white.Y); got != 255 {
+			t.Errorf("%d degrees: pixel %v = %d, want white", tt.degrees, tt.white, got)
+		}
+	}
+
+	for _, degrees := range []int{45, -90, 360} {
+		if _, err := RotateImage(src, degrees); err == nil {
+			t.Errorf("%d degrees: RotateImage() succeeded, want an error", degrees)
+		}
+	}
+}
+
+func TestFlipImage(t *testing.T) {
+	const w, h = 5, 3
+	// A white image with a single black pixel at (0,0)
+	src := solidImage(w, h, 255)
+	src.SetGray(0, 0, color.Gray{})
+
+	tests := []struct {
+		name                 string
+		horizontal, vertical bool
+		black                image.Point
+	}{
+		{"none", false, false, image.Pt(0, 0)},
+		{"horizontal", true, false, image.Pt(w-1, 0)},
+		{"vertical", false, true, image.Pt(0, h-1)},
+		{"both", true, true, image.Pt(w-1, h-1)},
+	}
+	for _, tt := range tests {
+		flipped := FlipImage(src, tt.horizontal, tt.vertical)
+		if got := flipped.Bounds(); got.Dx() != w || got.Dy() != h {
+			t.Errorf("%s: size %dx%d, want %dx%d", tt.name, got.Dx(), got.Dy(), w, h)
+			continue
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				want := uint8(255)
+				if image.Pt(x, y) == tt.black {
+					want = 0
+				}
+				if got := grayAt(flipped, x, y); got != want {
+					t.Errorf("%s: pixel (%d,%d) = %d, want %d", tt.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
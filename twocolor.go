@@ -0,0 +1,154 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/nfnt/resize"
+)
+
+// Color planes of two-color thermal paper, as used by the graphics commands
+const (
+	colorPlaneBlack byte = 49 // First color
+	colorPlaneRed   byte = 50 // Second color
+)
+
+// PrepareRedMask loads the mask image at config.RedMaskPath and lays it out to match
+// an image whose content was scaled to contentWidth x contentHeight pixels. The mask
+// goes through the same inversion, rotation, padding, mirroring and page placement
+// as the image, so both color planes stay in register.
+//
+// Every non-white pixel of the mask is printed in red. The mask is scaled with
+// nearest-neighbor sampling so hard edges of the separation are preserved.
+// If config.RedAlgo is set, the mask is instead scaled with Lanczos resampling
+// and dithered with that algorithm, so its gray tones print as red texture.
+func PrepareRedMask(config *Config, contentWidth, contentHeight int) (image.Image, error) {
+	mask, err := LoadImage(config.RedMaskPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load red mask: %w", err)
+	}
+	if contentWidth <= 0 || contentHeight <= 0 {
+		return nil, fmt.Errorf("no room for the red mask in a %dx%d image", contentWidth, contentHeight)
+	}
+
+	interp := resize.NearestNeighbor
+	if config.RedAlgo != nil {
+		interp = resize.Lanczos3
+	}
+	scaled, _, err := transformImage(mask, config, func(img image.Image) (image.Image, error) {
+		if img.Bounds().Dx() == contentWidth && img.Bounds().Dy() == contentHeight {
+			return img, nil
+		}
+		return resize.Resize(uint(contentWidth), uint(contentHeight), img, interp), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lay out red mask: %w", err)
+	}
+
+	if config.RedAlgo != nil {
+		dithered, err := ApplyDitheringConfig(scaled, config.withDitheringAlgo(*config.RedAlgo))
+		if err != nil {
+			return nil, fmt.Errorf("failed to dither red mask: %w", err)
+		}
+		scaled = dithered
+		logger().Debug("Red mask dithered", "algorithm", config.RedAlgo.String())
+	}
+
+	bounds := scaled.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	pixels := make([][]bool, height)
+	for y := 0; y < height; y++ {
+		pixels[y] = make([]bool, width)
+		for x := 0; x < width; x++ {
+			gray := color.GrayModel.Convert(scaled.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+			pixels[y][x] = gray.Y < 255
+		}
+	}
+
+	logger().Debug("Red mask prepared", "path", config.RedMaskPath, "width", width, "height", height)
+	return createMonochromeImage(pixels, width, height), nil
+}
+
+// GenerateTwoColorESCPOS generates commands printing img in black and mask in red on
+// two-color thermal paper. Both images must be monochrome and of the same size;
+// where the mask is black the pixel is printed red only.
+//
+// Both color planes are stored in the print buffer with GS 8 L (function 112) and
+// printed together with GS ( L (function 50). Config.PrintMode is not used.
+func GenerateTwoColorESCPOS(img, mask image.Image, config *Config) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if mask.Bounds().Dx() != width || mask.Bounds().Dy() != height {
+		return nil, fmt.Errorf("red mask size %dx%d does not match image size %dx%d",
+			mask.Bounds().Dx(), mask.Bounds().Dy(), width, height)
+	}
+
+	logger().Debug("Generating two-color commands", "width", width, "height", height)
+
+	blackData, err := convertToRasterFormat(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert image to raster format: %w", err)
+	}
+	redData, err := convertToRasterFormat(mask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert red mask to raster format: %w", err)
+	}
+
+	// Red takes precedence, so clear the black plane wherever the mask is set
+	for i := range blackData {
+		blackData[i] &^= redData[i]
+	}
+
+	var buf bytes.Buffer
+	writePrinterInit(&buf, config)
+
+	if config.DebugText != "" {
+		writeTextLine(&buf, config.DebugText, config)
+	}
+
+	writeGraphicsPlane(&buf, width, height, colorPlaneBlack, blackData)
+	if config.AppendChecksum {
+		writeChecksum(&buf, blackData, config.ChecksumType)
+	}
+	writeGraphicsPlane(&buf, width, height, colorPlaneRed, redData)
+	if config.AppendChecksum {
+		writeChecksum(&buf, redData, config.ChecksumType)
+	}
+
+	// GS ( L pL pH m fn: print the graphics data in the print buffer
+	writeJustification(&buf, config)
+	buf.Write([]byte{GS, '(', 'L', 2, 0, 48, 50})
+	resetJustification(&buf, config)
+
+	if err := writeImageBarcode(&buf, config); err != nil {
+		return nil, err
+	}
+
+	writeSmoothingReset(&buf, config)
+
+	writeFinalFeed(&buf, 3, config)
+
+	if config.cutType() != CutNone {
+		writePaperCut(&buf, config)
+	}
+
+	logger().Debug("Two-color command generation completed", "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
+
+// writeGraphicsPlane stores one color plane of raster graphics in the print buffer
+// using GS 8 L (function 112), which allows data larger than 64 KiB
+func writeGraphicsPlane(buf *bytes.Buffer, width, height int, plane byte, data []byte) {
+	// GS 8 L p1 p2 p3 p4 m fn a bx by c xL xH yL yH [data]
+	size := 10 + len(data)
+	buf.Write([]byte{GS, '8', 'L'})
+	buf.Write([]byte{byte(size), byte(size >> 8), byte(size >> 16), byte(size >> 24)})
+	buf.Write([]byte{48, 112, 48, 1, 1, plane}) // m fn a=monochrome bx=1 by=1 c
+	buf.Write([]byte{byte(width), byte(width >> 8)})
+	buf.Write([]byte{byte(height), byte(height >> 8)})
+	buf.Write(data)
+
+	logger().Debug("Wrote graphics plane", "plane", plane, "width", width, "height", height, "data_size", len(data))
+}
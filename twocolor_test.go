@@ -0,0 +1,71 @@
+package escposimg
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// blockImage returns an asymmetric black and white image of blocks, so that any
+// rotation, mirroring or shift changes it
+func blockImage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x/8+y/4)%3 != 0 && x < width-y {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestRedMaskRegistration(t *testing.T) {
+	src := blockImage(64, 40)
+	maskPath := writePNG(t, src)
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+	}{
+		{"defaults", func(c *Config) {}},
+		{"rotation", func(c *Config) { c.Rotation = 90 }},
+		{"flip horizontal", func(c *Config) { c.FlipHorizontal = true }},
+		{"flip vertical", func(c *Config) { c.FlipVertical = true }},
+		{"flip and transfer mirror", func(c *Config) { c.FlipHorizontal = true; c.TransferMirror = true }},
+		{"invert", func(c *Config) { c.Invert = true }},
+		{"padding", func(c *Config) { c.PaddingPx = Padding{Top: 3, Right: 5, Bottom: 7, Left: 9} }},
+		{"page width", func(c *Config) { c.PageWidthDots = 200 }},
+		{"page offset", func(c *Config) { c.PageWidthDots = 200; c.OffsetXPx = 30 }},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.PaperWidthMM = 20
+		config.NoScale = true
+		config.DitheringAlgo = DitheringThreshold
+		config.RedMaskPath = maskPath
+		tt.change(config)
+
+		img, content, err := prepareImage(context.Background(), src, config, 0)
+		if err != nil {
+			t.Fatalf("%s: prepareImage() error = %v", tt.name, err)
+		}
+		mask, err := PrepareRedMask(config, content.X, content.Y)
+		if err != nil {
+			t.Fatalf("%s: PrepareRedMask() error = %v", tt.name, err)
+		}
+		if img.Bounds().Size() != mask.Bounds().Size() {
+			t.Errorf("%s: mask size %v, want the image size %v", tt.name, mask.Bounds().Size(), img.Bounds().Size())
+			continue
+		}
+
+		// Image and mask come from the same source, so both planes must be identical
+		black, _ := convertToRasterFormat(img)
+		red, _ := convertToRasterFormat(mask)
+		if !bytes.Equal(black, red) {
+			t.Errorf("%s: red mask is not in register with the black plane", tt.name)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package escposimg
 
+import "time"
+
 // DitheringType represents the available dithering algorithms
 type DitheringType int
 
@@ -12,8 +14,30 @@ const (
 	DitheringSierraLite
 	DitheringJarvisJudiceNinke
 	DitheringShadura
+	DitheringStucki
+	DitheringSierra
+	DitheringHalftone
+	DitheringAdaptive
 )
 
+// DitheringTypes returns all available dithering algorithms
+func DitheringTypes() []DitheringType {
+	return []DitheringType{
+		DitheringFloydSteinberg,
+		DitheringAtkinson,
+		DitheringThreshold,
+		DitheringBayer,
+		DitheringBurkes,
+		DitheringSierraLite,
+		DitheringJarvisJudiceNinke,
+		DitheringShadura,
+		DitheringStucki,
+		DitheringSierra,
+		DitheringHalftone,
+		DitheringAdaptive,
+	}
+}
+
 // PrintMode defines the ESC/POS printing mode for images.
 //
 // ESC/POS supports two main approaches for printing bitmap images:
@@ -75,6 +99,154 @@ func (p PrintMode) String() string {
 	}
 }
 
+// Dialect selects the command set of the target printer
+type Dialect int
+
+const (
+	// DialectEpson emits Epson ESC/POS commands, understood by most receipt printers
+	DialectEpson Dialect = iota
+
+	// DialectStar emits the StarPRNT raster command (ESC GS S) and Star cut
+	// command (ESC d) for Star Micronics printers in StarPRNT emulation
+	DialectStar
+)
+
+// String returns the string representation of the dialect
+func (d Dialect) String() string {
+	switch d {
+	case DialectEpson:
+		return "epson"
+	case DialectStar:
+		return "star"
+	default:
+		return "unknown"
+	}
+}
+
+// RasterScale is the m parameter of the GS v 0 raster command, selecting how the
+// printer enlarges the image. Some manuals call it the raster density, as
+// doubling a dimension halves the dot density along it.
+type RasterScale uint8
+
+const (
+	// RasterScaleNormal prints the image at its size (m=0)
+	RasterScaleNormal RasterScale = iota
+	// RasterScaleDoubleWidth prints every dot twice as wide (m=1)
+	RasterScaleDoubleWidth
+	// RasterScaleDoubleHeight prints every dot twice as high (m=2)
+	RasterScaleDoubleHeight
+	// RasterScaleQuadruple prints every dot twice as wide and high (m=3)
+	RasterScaleQuadruple
+)
+
+// String returns the string representation of the raster scale
+func (r RasterScale) String() string {
+	switch r {
+	case RasterScaleNormal:
+		return "normal"
+	case RasterScaleDoubleWidth:
+		return "double-width"
+	case RasterScaleDoubleHeight:
+		return "double-height"
+	case RasterScaleQuadruple:
+		return "quadruple"
+	default:
+		return "unknown"
+	}
+}
+
+// Alignment is the horizontal position of images narrower than the paper, set with
+// the ESC a justification command
+type Alignment int
+
+const (
+	// AlignLeft prints images at the left edge (n=0)
+	AlignLeft Alignment = iota
+	// AlignCenter centers images on the paper (n=1)
+	AlignCenter
+	// AlignRight prints images at the right edge (n=2)
+	AlignRight
+)
+
+// String returns the string representation of the alignment
+func (a Alignment) String() string {
+	switch a {
+	case AlignLeft:
+		return "left"
+	case AlignCenter:
+		return "center"
+	case AlignRight:
+		return "right"
+	default:
+		return "unknown"
+	}
+}
+
+// CutType selects how the paper is cut after printing
+type CutType int
+
+const (
+	// CutNone sends no cut command unless CutPaper is set, which selects CutPartial
+	CutNone CutType = iota
+	// CutPartial leaves a small uncut tab (GS V 1, Star ESC d 1)
+	CutPartial
+	// CutFull cuts the paper completely (GS V 0, Star ESC d 0)
+	CutFull
+)
+
+// String returns the string representation of the cut type
+func (c CutType) String() string {
+	switch c {
+	case CutNone:
+		return "none"
+	case CutPartial:
+		return "partial"
+	case CutFull:
+		return "full"
+	default:
+		return "unknown"
+	}
+}
+
+// SeparatorStyle selects the pattern of the rules printed with TopRule and BottomRule
+type SeparatorStyle int
+
+const (
+	// SeparatorSolid is a solid black bar
+	SeparatorSolid SeparatorStyle = iota
+	// SeparatorDots is a row of round dots as wide as the rule is high
+	SeparatorDots
+	// SeparatorDashes is a dashed bar
+	SeparatorDashes
+	// SeparatorWave is a sine wave filling the rule height
+	SeparatorWave
+	// SeparatorDoubleLine is two thin lines at the top and bottom of the rule
+	SeparatorDoubleLine
+)
+
+// SeparatorStyles returns all available separator styles
+func SeparatorStyles() []SeparatorStyle {
+	return []SeparatorStyle{SeparatorSolid, SeparatorDots, SeparatorDashes, SeparatorWave, SeparatorDoubleLine}
+}
+
+// String returns the string representation of the separator style
+func (s SeparatorStyle) String() string {
+	switch s {
+	case SeparatorSolid:
+		return "solid"
+	case SeparatorDots:
+		return "dots"
+	case SeparatorDashes:
+		return "dashes"
+	case SeparatorWave:
+		return "wave"
+	case SeparatorDoubleLine:
+		return "double-line"
+	default:
+		return "unknown"
+	}
+}
+
 // String returns the string representation of the dithering type
 func (d DitheringType) String() string {
 	switch d {
@@ -94,6 +266,14 @@ func (d DitheringType) String() string {
 		return "jarvis-judice-ninke"
 	case DitheringShadura:
 		return "shadura"
+	case DitheringStucki:
+		return "stucki"
+	case DitheringSierra:
+		return "sierra"
+	case DitheringHalftone:
+		return "halftone"
+	case DitheringAdaptive:
+		return "adaptive"
 	default:
 		return "unknown"
 	}
@@ -104,12 +284,100 @@ type Config struct {
 	// Paper width in millimeters (default: 80mm)
 	PaperWidthMM int
 
-	// Printer DPI (default: 203 DPI)
+	// Printable width in dots. When positive it is used instead of the width
+	// calculated from PaperWidthMM and the DPI, e.g. with the value reported by
+	// NetworkOutput.QueryWidth.
+	WidthDots int
+
+	// Width of the printed page in dots. When larger than the image, the image is
+	// placed on the page without scaling, centered or at OffsetXPx from the left
+	// edge. Combined with WidthDots it separates the content width from the page
+	// width, e.g. to center a 384 dot logo on 576 dot paper.
+	PageWidthDots int
+
+	// Distance in dots of the image from the left edge of the page (requires
+	// PageWidthDots; zero centers the image)
+	OffsetXPx int
+
+	// Position of images narrower than the paper (default: AlignLeft). Unlike
+	// PageWidthDots no padding is sent: the printer aligns the image with ESC a,
+	// which is not supported for Star printers.
+	Alignment Alignment
+
+	// Printer DPI (default: 203 DPI). Sets both DPIX and DPIY unless they are given.
 	DPI int
 
+	// Horizontal printer DPI for printers with non-square dots (0 uses DPI)
+	DPIX int
+
+	// Vertical printer DPI for printers with non-square dots (0 uses DPI)
+	DPIY int
+
+	// Clockwise rotation in degrees (0, 90, 180 or 270) applied losslessly before
+	// scaling, e.g. to print landscape images along the paper. Applied before
+	// RotateDegrees.
+	Rotation int
+
+	// Rotate the image clockwise by this angle in degrees before scaling, e.g. to
+	// straighten a crooked scan. Uncovered corners are filled white.
+	RotateDegrees float64
+
+	// Interpolation used when scaling the image to the paper width (default: ScaleFilterLanczos3)
+	ScaleFilter ScaleFilter
+
+	// Rounding of the scaled image height to whole pixels (default: HeightRoundNearest)
+	HeightRounding HeightRounding
+
+	// Print the image at its original pixel size instead of scaling it to the
+	// paper width. Images wider than the paper are still scaled down, with a
+	// warning (an error in Strict mode), as the printer would truncate them.
+	NoScale bool
+
 	// Dithering algorithm to use
 	DitheringAlgo DitheringType
 
+	// Return an error for an unknown DitheringAlgo value instead of falling back
+	// to Floyd-Steinberg with a warning
+	StrictDithering bool
+
+	// Alternate the scan direction of error-diffusion dithering every row, mirroring
+	// the kernel on right-to-left rows. Reduces directional artifacts ("worming")
+	// on smooth gradients; has no effect on Threshold and Bayer dithering.
+	Serpentine bool
+
+	// Factor applied to the quantization error before error-diffusion algorithms
+	// distribute it (default: 1.0), e.g. 0.8 for lighter, smoother output or 1.2
+	// for more contrast. Zero uses 1.0; ordered dithers are not affected.
+	DiffusionScale float64
+
+	// Size of the Bayer matrix used by DitheringBayer: 2, 4 or 8 (zero uses 4).
+	// Larger matrices give a finer pattern with more gray levels on high-DPI printers.
+	BayerSize int
+
+	// Optional callback reporting the progress of error-diffusion dithering.
+	// It is called every 32 rows and after the last row; nil disables reporting.
+	DitherProgress func(rowsDone, totalRows int)
+
+	// Convert colors to gray in linear light using the BT.709 weights, which is
+	// colorimetrically correct but slower than the default BT.601 approximation
+	// on gamma-encoded values. Improves tone accuracy for colored images.
+	AccurateGray bool
+
+	// Gamma of the source colors (default: 2.2). The channels are linearized before
+	// the luminance weighting and re-encoded afterwards, which keeps saturated
+	// colors from printing too dark. Zero or 1.0 weights the encoded values directly.
+	// Ignored when AccurateGray is set.
+	Gamma float64
+
+	// Print the negative of the image, so that white-on-black artwork prints
+	// black on white. The image is inverted before the grayscale conversion,
+	// Gamma and Threshold; padding stays white.
+	Invert bool
+
+	// Gray level (1-255) below which pixels print black. Zero uses the
+	// algorithm's default threshold (see DefaultThreshold).
+	Threshold uint8
+
 	// ESC/POS printing mode for images (default: PrintModeRaster).
 	//
 	// Determines which ESC/POS command sequence to use for image printing:
@@ -120,6 +388,88 @@ type Config struct {
 	// compatibility or when experiencing printer communication issues.
 	PrintMode PrintMode
 
+	// ESC * mode in PrintModeBitImage (default: BitImageMode8Dot). The 24-dot
+	// modes BitImageMode24DotSingle and BitImageMode24DotDouble pack bands of
+	// 24 pixels into 3 bytes per column for finer output on modern printers.
+	BitImageMode byte
+
+	// Command set of the printer (default: DialectEpson). With DialectStar images
+	// are printed with the StarPRNT raster command regardless of PrintMode, and
+	// the Epson-only RasterScale, Smoothing and motion unit settings are ignored.
+	Dialect Dialect
+
+	// Keep raster images in the printer's memory for reprinting. GS v 0 clears its
+	// data after printing and has no flag to keep it, so the image is sent as a
+	// downloaded bit image (GS *) and printed with GS / m, where m is the
+	// RasterScale. It stays in memory until it is replaced, ESC @ is received or
+	// the printer is turned off, and can be printed again with PrintBufferedImage.
+	// Only images of up to 1536 blocks of 8x8 dots (e.g. 512x192) fit; larger
+	// images are printed with GS v 0 and a warning.
+	PreserveBuffer bool
+
+	// Enlargement applied by the printer in raster mode (GS v 0 m parameter).
+	// A double-width image that would print wider than the paper is scaled
+	// down with a warning; prepare it for half the paper width to avoid this.
+	RasterScale RasterScale
+
+	// Raster scales accepted by the printer. When set, a RasterScale outside
+	// this list is rejected with an error instead of being sent to a printer
+	// that would silently ignore it. Empty means all scales are allowed.
+	SupportedRasterModes []RasterScale
+
+	// Enable the printer's smoothing mode (GS b 1) while printing.
+	//
+	// Smoothing is applied by the printer firmware and can improve the look of
+	// text and line art. Not all printers support it; unsupported printers may
+	// ignore the command or print stray characters.
+	Smoothing bool
+
+	// Horizontal and vertical motion units (GS P) set during initialization, as
+	// 1/x and 1/y inch (0-255). Dot-based feeds such as ESC J are measured in the
+	// vertical unit, so setting it to the DPI makes them behave the same on every
+	// printer. Zero for both leaves the printer default; zero for one axis selects
+	// that axis' default.
+	MotionUnitX int
+	MotionUnitY int
+
+	// Whitespace in pixels added around the image before dithering. The image is
+	// scaled so that left padding, image and right padding fill the paper width.
+	// Negative values are rejected.
+	PaddingPx Padding
+
+	// Mirror the image left-to-right so it reads correctly after being
+	// transferred, e.g. when printing on iron-on transfer paper
+	TransferMirror bool
+
+	// Mirror the image left-to-right and/or top-to-bottom before dithering.
+	// FlipHorizontal combined with TransferMirror cancels out.
+	FlipHorizontal bool
+	FlipVertical   bool
+
+	// Optional path of a mask image for two-color paper. Non-white mask pixels
+	// are printed in red, the dithered image in black (see GenerateTwoColorESCPOS).
+	RedMaskPath string
+
+	// Optional dithering algorithms for the black and red planes of two-color
+	// output. BlackAlgo replaces DitheringAlgo for the image; with RedAlgo the
+	// mask is scaled smoothly and dithered as a grayscale image instead of
+	// printing every non-white pixel red. Nil keeps the default behavior.
+	BlackAlgo *DitheringType
+	RedAlgo   *DitheringType
+
+	// Print a rule spanning the paper width above and/or below the image. Rules are
+	// printed directly, without PreserveBuffer and AppendChecksum.
+	TopRule    bool
+	BottomRule bool
+
+	// Height of the rules in pixels (default: DefaultRuleHeightPx)
+	RuleHeightPx int
+
+	// Pattern of the rules (default: SeparatorSolid), e.g. SeparatorWave for a
+	// decorative separator. Patterns other than solid need a RuleHeightPx of
+	// about 8 or more to be recognizable.
+	SeparatorArt SeparatorStyle
+
 	// Save dithered image for debugging
 	DebugOutput bool
 
@@ -129,8 +479,105 @@ type Config struct {
 	// Optional debug text to print before image
 	DebugText string
 
-	// Send paper cut command after printing
+	// Optional barcode printed below the image with GS k, e.g. a product code.
+	// BarcodeType is the GS k barcode system from BarcodeUPCA to BarcodeCODE128
+	// (used when zero); CODE128 data without a code set prefix is sent as code set B.
+	BarcodeData string
+	BarcodeType BarcodeType
+
+	// Height of barcode bars in dots (1-255; 0 keeps the printer default) and
+	// position of their human readable text (default: HRIBelow). Used for
+	// BarcodeData and GenerateBarcode.
+	BarcodeHeightDots int
+	BarcodeHRI        HRIPosition
+
+	// Keyword of a PNG text chunk (tEXt, zTXt or iTXt) holding the barcode value.
+	// When the image carries it, the value replaces BarcodeData, so product labels
+	// get a scannable barcode instead of a rasterized one.
+	BarcodeMetaKey string
+
+	// Print text (e.g. DebugText) white on black using GS B
+	ReverseVideo bool
+
+	// Send paper cut command after printing (a partial cut unless CutType is set)
 	CutPaper bool
+
+	// Kind of cut after printing (default: CutNone). Any type other than CutNone
+	// cuts the paper regardless of CutPaper.
+	CutType CutType
+
+	// The printer has no cutter: cut commands are never sent, even with CutPaper
+	// set, as some printers without a cutter print them as garbage characters
+	NoCutter bool
+
+	// Print a dashed tear line where the paper would have been cut (requires NoCutter)
+	TearLine bool
+
+	// Omit the line feeds after the image. Together with CutPaper disabled, the
+	// output ends with the image data, e.g. to spool many images into one file
+	// with custom separators.
+	SkipFinalFeed bool
+
+	// Number of line feeds after the image, before the cut, where a pointer to
+	// zero writes none. Nil keeps the default of the generator (3, or 2 in
+	// PrintModeBitImage).
+	FeedLinesBeforeCut *int
+
+	// Write a human-readable metadata file (source image, settings, timestamp and
+	// checksum) next to the output file, named like it with a ".meta" suffix. As
+	// printers cannot skip comments in the data, the metadata is kept separate.
+	// Only has an effect with FileOutput.
+	FileComment bool
+
+	// Upper limit for the size of the generated commands in bytes. When exceeded,
+	// the image is scaled to a smaller width until it fits (zero disables the limit)
+	MaxOutputBytes int
+
+	// Abort processing with an error when scaling and dithering take longer than
+	// this, protecting services from pathological inputs. Zero disables the limit.
+	// Error diffusion is interrupted within a few rows; scaling is checked after
+	// it completes.
+	MaxProcessingTime time.Duration
+
+	// Pause after sending the printer reset (ESC @) before sending the rest of the
+	// job, for printers that drop commands while resetting. Applied when writing
+	// to the output, so it only helps with outputs that send data immediately
+	// (e.g. network), not with buffered or file outputs.
+	InitDelay time.Duration
+
+	// Split the image into tiles of this many pixels in height, each printed as
+	// its own receipt (zero disables tiling)
+	TileHeightPx int
+
+	// Frame each tile with crop-corner ticks so the printed pieces can be aligned
+	RegistrationMarks bool
+
+	// Blank paper in dots between the image and the barcode of a label (see ProcessLabel)
+	LabelGapDots int
+
+	// Number of blank labels of LabelHeightMM fed after the image, e.g. to reach
+	// the peel-off position on continuous label stock. The paper is fed with ESC J
+	// in vertical motion units (see MotionUnitY).
+	TrailingBlankLabels int
+	LabelHeightMM       float64
+
+	// Append a checksum over the image data after each image command, for custom
+	// firmware that validates the integrity of received images
+	AppendChecksum bool
+
+	// Checksum algorithm used when AppendChecksum is set (default: ChecksumXOR)
+	ChecksumType ChecksumType
+
+	// Turn warnings (e.g. about upscaling) into errors, for automated validation
+	Strict bool
+
+	// Silence warnings instead of logging them (ignored when Strict is set)
+	SuppressWarnings bool
+}
+
+// Padding describes whitespace in pixels on each side of an image
+type Padding struct {
+	Top, Right, Bottom, Left int
 }
 
 // DefaultConfig returns a configuration with sensible defaults
@@ -139,6 +586,8 @@ func DefaultConfig() *Config {
 		PaperWidthMM:   80,
 		DPI:            203,
 		DitheringAlgo:  DitheringFloydSteinberg,
+		Gamma:          2.2,
+		DiffusionScale: 1.0,
 		PrintMode:      PrintModeRaster, // Default to modern raster mode
 		DebugOutput:    false,
 		DebugImagePath: "debug_output.png",
@@ -166,9 +615,43 @@ const (
 	PaperWidth80mm = 80
 )
 
-// CalculatePixelWidth calculates the pixel width based on paper width and DPI
+// DefaultRuleHeightPx is the height of the top and bottom rules when Config.RuleHeightPx is zero
+const DefaultRuleHeightPx = 4
+
+// CalculatePixelWidth calculates the pixel width based on paper width and horizontal DPI,
+// or returns WidthDots when it is set
 func (c *Config) CalculatePixelWidth() int {
+	if c.WidthDots > 0 {
+		return c.WidthDots
+	}
+
 	// Convert mm to inches, then multiply by DPI
 	inches := float64(c.PaperWidthMM) / 25.4
-	return int(inches * float64(c.DPI))
+	return int(inches * float64(c.HorizontalDPI()))
+}
+
+// HorizontalDPI returns the effective horizontal resolution (DPIX, falling back to DPI)
+func (c *Config) HorizontalDPI() int {
+	if c.DPIX > 0 {
+		return c.DPIX
+	}
+	return c.DPI
+}
+
+// VerticalDPI returns the effective vertical resolution (DPIY, falling back to DPI)
+func (c *Config) VerticalDPI() int {
+	if c.DPIY > 0 {
+		return c.DPIY
+	}
+	return c.DPI
+}
+
+// VerticalScale returns the factor applied to image height so that printers with
+// non-square dots reproduce the original aspect ratio. It is 1.0 for square dots.
+func (c *Config) VerticalScale() float64 {
+	dpiX := c.HorizontalDPI()
+	if dpiX <= 0 {
+		return 1.0
+	}
+	return float64(c.VerticalDPI()) / float64(dpiX)
 }
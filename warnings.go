@@ -0,0 +1,40 @@
+package escposimg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// warn reports a condition that may lead to unexpected output.
+//
+// Depending on the configuration the warning is logged (default), silently
+// dropped (SuppressWarnings) or returned as an error (Strict). Strict takes
+// precedence over SuppressWarnings. The arguments are slog-style key/value pairs.
+func (c *Config) warn(msg string, args ...any) error {
+	if c.Strict {
+		return fmt.Errorf("%s%s", msg, formatWarningArgs(args))
+	}
+	if c.SuppressWarnings {
+		return nil
+	}
+	logger().Warn(msg, args...)
+	return nil
+}
+
+// formatWarningArgs renders slog-style key/value pairs for error messages
+func formatWarningArgs(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(" (")
+	for i := 0; i+1 < len(args); i += 2 {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%v=%v", args[i], args[i+1])
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
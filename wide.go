@@ -0,0 +1,104 @@
+package escposimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+)
+
+// ProcessWideTwoPass prints an image twice as wide as the paper in two passes.
+// See GenerateWideTwoPassCommands.
+func ProcessWideTwoPass(imagePath string, config *Config, output OutputMethod) error {
+	data, err := GenerateWideTwoPassCommands(imagePath, config)
+	if err != nil {
+		return err
+	}
+
+	if err := writeCommands(output, data, config.InitDelay); err != nil {
+		return fmt.Errorf("failed to write to output: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	logger().Info("Two-pass processing completed successfully")
+	return nil
+}
+
+// GenerateWideTwoPassCommands scales an image to twice the printable width, dithers
+// it as a whole and splits it at the printable width. The left half is printed as
+// a first receipt and the right half as a second one, so that both strips can be
+// laid side by side to cover media wider than the printer; with
+// config.RegistrationMarks each half is framed by alignment marks.
+//
+// Images that fit the paper (e.g. with NoScale) are printed in a single pass.
+func GenerateWideTwoPassCommands(imagePath string, config *Config) ([]byte, error) {
+	width := config.CalculatePixelWidth()
+
+	// Lay out and dither the whole image, so the halves join without a seam
+	wideConfig := *config
+	wideConfig.WidthDots = 2 * width
+	wideConfig.PageWidthDots = 0
+	img, err := PrepareImage(imagePath, &wideConfig)
+	if err != nil {
+		return nil, err
+	}
+	if config.BarcodeMetaKey != "" {
+		data, err := os.ReadFile(imagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image metadata: %w", err)
+		}
+		config = withBarcodeMeta(data, config)
+	}
+
+	halves := splitAtWidth(img, width)
+	if len(halves) == 1 {
+		if err := config.warn("Image fits the paper and is printed in a single pass",
+			"image_width", img.Bounds().Dx(), "paper_width", width); err != nil {
+			return nil, err
+		}
+	}
+
+	// The barcode is printed once, below the right half
+	passConfig := *config
+	passConfig.BarcodeData = ""
+
+	var buf bytes.Buffer
+	for i, half := range halves {
+		if config.RegistrationMarks {
+			half = AddRegistrationMarks(half)
+		}
+		if i == len(halves)-1 {
+			passConfig.BarcodeData = config.BarcodeData
+		}
+		data, err := GenerateESCPOS(half, &passConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate pass %d: %w", i+1, err)
+		}
+		buf.Write(data)
+	}
+
+	logger().Debug("Two-pass command generation completed", "passes", len(halves), "total_bytes", buf.Len())
+	return buf.Bytes(), nil
+}
+
+// splitAtWidth splits an image into a left part of at most width pixels and a
+// right part holding the remaining columns, if any
+func splitAtWidth(img image.Image, width int) []image.Image {
+	bounds := img.Bounds()
+	split := min(bounds.Min.X+width, bounds.Max.X)
+	rects := []image.Rectangle{image.Rect(bounds.Min.X, bounds.Min.Y, split, bounds.Max.Y)}
+	if split < bounds.Max.X {
+		rects = append(rects, image.Rect(split, bounds.Min.Y, bounds.Max.X, bounds.Max.Y))
+	}
+
+	parts := make([]image.Image, 0, len(rects))
+	for _, rect := range rects {
+		part := image.NewGray(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+		draw.Draw(part, part.Bounds(), img, rect.Min, draw.Src)
+		parts = append(parts, part)
+	}
+	return parts
+}